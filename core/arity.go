@@ -0,0 +1,11 @@
+package core
+
+import "fmt"
+
+// ArityError is raised when a multi-arity fn has no
+// arity for the number of arguments it got called with.
+type ArityError int
+
+func (n ArityError) Error() string {
+	return fmt.Sprintf("wrong number of arguments (%d) passed to fn", int(n))
+}
@@ -0,0 +1,26 @@
+package core
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// BigInt parses the value of a 123N literal.
+func BigInt(s string) *big.Int {
+	n, ok := new(big.Int).SetString(s, 0)
+	if !ok {
+		panic(fmt.Sprintf("invalid big integer: %q", s))
+	}
+
+	return n
+}
+
+// Ratio parses the value of a 1/3 literal.
+func Ratio(s string) *big.Rat {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		panic(fmt.Sprintf("invalid ratio: %q", s))
+	}
+
+	return r
+}
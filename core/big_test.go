@@ -0,0 +1,30 @@
+package core
+
+import "testing"
+
+func TestBigLiterals(t *testing.T) {
+	if got := BigInt("123456789012345678901234567890").String(); got != "123456789012345678901234567890" {
+		t.Errorf("BigInt = %s", got)
+	}
+
+	if got := BigInt("0x10").String(); got != "16" {
+		t.Errorf("BigInt(0x10) = %s", got)
+	}
+
+	if got := Ratio("2/4").String(); got != "1/2" {
+		t.Errorf("Ratio(2/4) = %s", got)
+	}
+}
+
+func TestBigLiteralsPanic(t *testing.T) {
+	for _, f := range []func(){func() { BigInt("x") }, func() { Ratio("1/0") }} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("didn't panic")
+				}
+			}()
+			f()
+		}()
+	}
+}
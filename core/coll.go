@@ -0,0 +1,355 @@
+package core
+
+import (
+	"fmt"
+	"iter"
+	"reflect"
+	"slices"
+)
+
+// The collections are handled through these, so that vectors
+// compare equal to []Any, and a sorted map to a hash map.
+type (
+	indexed interface {
+		Count() int
+		Nth(i int) Any
+	}
+
+	associative interface {
+		Count() int
+		Find(key Any) (Any, bool)
+		All() iter.Seq2[Any, Any]
+	}
+
+	setLike interface {
+		Count() int
+		Contains(x Any) bool
+		All() iter.Seq[Any]
+	}
+)
+
+// Conj returns coll with xs added: at the end of a vector, as
+// elements of a set or as [key value] entries of a map. (conj nil x)
+// is a vector of x.
+func Conj(coll Any, xs ...Any) Any {
+	switch c := coll.(type) {
+	case nil:
+		return Vector(xs...)
+	case *PersistentVector:
+		for _, x := range xs {
+			c = c.Conj(x)
+		}
+		return c
+	case *PersistentHashSet:
+		for _, x := range xs {
+			c = c.Conj(x)
+		}
+		return c
+	case *PersistentSortedSet:
+		for _, x := range xs {
+			c = c.Conj(x)
+		}
+		return c
+	case *PersistentHashMap, *PersistentSortedMap:
+		for _, x := range xs {
+			coll = Assoc(coll, Nth(x, 0), Nth(x, 1))
+		}
+		return coll
+	case []Any:
+		return append(slices.Clip(c), xs...)
+	}
+
+	panic(fmt.Sprintf("can't conj onto %T", coll))
+}
+
+// Assoc returns coll with the keys in kvs set to their values, for
+// a vector the keys are indices. (assoc nil k v) is a hash map.
+func Assoc(coll Any, kvs ...Any) Any {
+	if len(kvs)%2 != 0 {
+		panic("assoc needs an even number of keys and values!")
+	}
+
+	switch c := coll.(type) {
+	case nil:
+		return HashMap(kvs...)
+	case *PersistentVector:
+		for i := 0; i < len(kvs); i += 2 {
+			c = c.Assoc(toIndex(kvs[i]), kvs[i+1])
+		}
+		return c
+	case *PersistentHashMap:
+		for i := 0; i < len(kvs); i += 2 {
+			c = c.Assoc(kvs[i], kvs[i+1])
+		}
+		return c
+	case *PersistentSortedMap:
+		for i := 0; i < len(kvs); i += 2 {
+			c = c.Assoc(kvs[i], kvs[i+1])
+		}
+		return c
+	}
+
+	panic(fmt.Sprintf("can't assoc onto %T", coll))
+}
+
+// Dissoc returns the map m without keys.
+func Dissoc(m Any, keys ...Any) Any {
+	switch c := m.(type) {
+	case nil:
+		return nil
+	case *PersistentHashMap:
+		for _, k := range keys {
+			c = c.Dissoc(k)
+		}
+		return c
+	case *PersistentSortedMap:
+		for _, k := range keys {
+			c = c.Dissoc(k)
+		}
+		return c
+	}
+
+	panic(fmt.Sprintf("can't dissoc from %T", m))
+}
+
+// Disj returns the set s without xs.
+func Disj(s Any, xs ...Any) Any {
+	switch c := s.(type) {
+	case nil:
+		return nil
+	case *PersistentHashSet:
+		for _, x := range xs {
+			c = c.Disj(x)
+		}
+		return c
+	case *PersistentSortedSet:
+		for _, x := range xs {
+			c = c.Disj(x)
+		}
+		return c
+	}
+
+	panic(fmt.Sprintf("can't disj from %T", s))
+}
+
+// IsContains reports whether key is in coll: an element of a set, a
+// key of a map or a valid index of a vector or string.
+func IsContains(coll Any, key Any) bool {
+	switch c := coll.(type) {
+	case nil:
+		return false
+	case setLike:
+		return c.Contains(key)
+	case associative:
+		_, ok := c.Find(key)
+		return ok
+	case indexed:
+		i, ok := key.(int)
+		return ok && 0 <= i && i < c.Count()
+	}
+
+	if i, ok := key.(int); ok && isIndexable(coll) {
+		return 0 <= i && i < Count(coll)
+	}
+
+	_, ok := Find(coll, key)
+	return ok
+}
+
+func isIndexable(coll Any) bool {
+	switch reflect.ValueOf(coll).Kind() {
+	case reflect.Slice, reflect.Array, reflect.String:
+		return true
+	}
+
+	return false
+}
+
+// Find returns the value of key in the map m and whether m has it.
+func Find(m Any, key Any) (Any, bool) {
+	switch c := m.(type) {
+	case nil:
+		return nil, false
+	case associative:
+		return c.Find(key)
+	case map[Any]Any:
+		v, ok := c[key]
+		return v, ok
+	}
+
+	v := reflect.ValueOf(m)
+	if v.Kind() != reflect.Map {
+		panic(fmt.Sprintf("can't look up a key in %T", m))
+	}
+
+	k := reflect.ValueOf(key)
+	if key == nil || !k.Type().AssignableTo(v.Type().Key()) {
+		return nil, false
+	}
+
+	if val := v.MapIndex(k); val.IsValid() {
+		return val.Interface(), true
+	}
+
+	return nil, false
+}
+
+// IsMap reports whether x is a map, persistent or not (sets aren't).
+func IsMap(x Any) bool {
+	switch x.(type) {
+	case associative, map[Any]Any:
+		return true
+	case Set:
+		return false
+	}
+
+	return x != nil && reflect.ValueOf(x).Kind() == reflect.Map
+}
+
+// Transient returns a transient copy of a persistent vector, hash map
+// or hash set, for conj!, assoc! and dissoc! to change in place.
+func Transient(coll Any) Any {
+	switch c := coll.(type) {
+	case *PersistentVector:
+		return c.Transient()
+	case *PersistentHashMap:
+		return c.Transient()
+	case *PersistentHashSet:
+		return c.Transient()
+	}
+
+	panic(fmt.Sprintf("can't make a transient of %T", coll))
+}
+
+// PersistentBang returns the collection a transient built, which
+// can't be changed any further.
+func PersistentBang(t Any) Any {
+	switch c := t.(type) {
+	case *TransientVector:
+		return c.Persistent()
+	case *TransientHashMap:
+		return c.Persistent()
+	case *TransientHashSet:
+		return c.Persistent()
+	}
+
+	panic(fmt.Sprintf("%T isn't a transient", t))
+}
+
+// ConjBang adds xs to a transient vector or set in place.
+func ConjBang(t Any, xs ...Any) Any {
+	switch c := t.(type) {
+	case *TransientVector:
+		for _, x := range xs {
+			c.Conj(x)
+		}
+		return c
+	case *TransientHashSet:
+		for _, x := range xs {
+			c.Conj(x)
+		}
+		return c
+	case *TransientHashMap:
+		for _, x := range xs {
+			c.Assoc(Nth(x, 0), Nth(x, 1))
+		}
+		return c
+	}
+
+	panic(fmt.Sprintf("can't conj! onto %T", t))
+}
+
+// AssocBang sets the keys in kvs to their values in a transient map
+// or vector in place.
+func AssocBang(t Any, kvs ...Any) Any {
+	if len(kvs)%2 != 0 {
+		panic("assoc! needs an even number of keys and values!")
+	}
+
+	switch c := t.(type) {
+	case *TransientVector:
+		for i := 0; i < len(kvs); i += 2 {
+			c.Assoc(toIndex(kvs[i]), kvs[i+1])
+		}
+		return c
+	case *TransientHashMap:
+		for i := 0; i < len(kvs); i += 2 {
+			c.Assoc(kvs[i], kvs[i+1])
+		}
+		return c
+	}
+
+	panic(fmt.Sprintf("can't assoc! onto %T", t))
+}
+
+// DissocBang removes keys from a transient map in place.
+func DissocBang(t Any, keys ...Any) Any {
+	c, ok := t.(*TransientHashMap)
+	if !ok {
+		panic(fmt.Sprintf("can't dissoc! from %T", t))
+	}
+
+	for _, k := range keys {
+		c.Dissoc(k)
+	}
+	return c
+}
+
+// DisjBang removes xs from a transient set in place.
+func DisjBang(t Any, xs ...Any) Any {
+	c, ok := t.(*TransientHashSet)
+	if !ok {
+		panic(fmt.Sprintf("can't disj! from %T", t))
+	}
+
+	for _, x := range xs {
+		c.Disj(x)
+	}
+	return c
+}
+
+func toIndex(i Any) int {
+	n, ok := i.(int)
+	if !ok {
+		panic(fmt.Sprintf("vector index must be an int, got %T", i))
+	}
+
+	return n
+}
+
+// convertColl turns a persistent vector or map into the []Any or
+// map[Any]Any a typed parameter wants.
+func convertColl(v Any, typ reflect.Type) (Any, bool) {
+	switch c := v.(type) {
+	case *PersistentVector:
+		if typ == reflect.TypeFor[[]Any]() {
+			return c.Slice(), true
+		}
+	case associative:
+		if typ == reflect.TypeFor[map[Any]Any]() {
+			m := make(map[Any]Any, c.Count())
+			for k, v := range c.All() {
+				m[k] = v
+			}
+			return m, true
+		}
+	}
+
+	return nil, false
+}
+
+func hashEntries(entries iter.Seq2[Any, Any]) uint32 {
+	var h uint32
+	for k, v := range entries {
+		h += Hash(k) ^ Hash(v)
+	}
+	return h
+}
+
+func hashUnordered(elems iter.Seq[Any]) uint32 {
+	var h uint32
+	for x := range elems {
+		h += Hash(x)
+	}
+	return h
+}
@@ -0,0 +1,422 @@
+package core
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestVector(t *testing.T) {
+	// around the tail, the first and second trie levels
+	sizes := []int{0, 1, 31, 32, 33, 64, 1024, 1056, 1057, 32*32*32 + 33}
+
+	for _, n := range sizes {
+		v := Vector()
+		for i := 0; i < n; i++ {
+			v = v.Conj(i)
+		}
+
+		if v.Count() != n {
+			t.Fatalf("%d conjs: count is %d", n, v.Count())
+		}
+		for i := 0; i < n; i++ {
+			if v.Nth(i) != i {
+				t.Fatalf("%d conjs: element %d is %v", n, i, v.Nth(i))
+			}
+		}
+
+		xs := make([]Any, n)
+		for i := range xs {
+			xs[i] = i
+		}
+		if !Equal(v, Vector(xs...)) {
+			t.Errorf("%d conjs: not equal to Vector of the same elements", n)
+		}
+
+		if n == 0 {
+			continue
+		}
+
+		for _, i := range []int{0, n / 2, n - 1} {
+			w := v.Assoc(i, -1)
+			if w.Nth(i) != -1 || v.Nth(i) != i {
+				t.Errorf("%d conjs: assoc %d gave %v, left the original %v", n, i, w.Nth(i), v.Nth(i))
+			}
+		}
+
+		for k := n; k > 0; k-- {
+			v = v.Pop()
+			if v.Count() != k-1 || (k > 1 && v.Peek() != k-2) {
+				t.Fatalf("%d conjs: pop to %d gave count %d, last %v", n, k-1, v.Count(), v.Peek())
+			}
+		}
+	}
+}
+
+func TestVectorOutOfRange(t *testing.T) {
+	v := Vector(1, 2, 3)
+
+	tests := []struct {
+		name string
+		f    func()
+	}{
+		{"nth -1", func() { v.Nth(-1) }},
+		{"nth count", func() { v.Nth(3) }},
+		{"assoc past the end", func() { v.Assoc(4, 0) }},
+		{"pop empty", func() { Vector().Pop() }},
+	}
+
+	for _, test := range tests {
+		if recovered(test.f) == nil {
+			t.Errorf("%s didn't panic", test.name)
+		}
+	}
+}
+
+func TestHashMap(t *testing.T) {
+	for _, n := range []int{0, 1, 10, 1000, 20000} {
+		m := HashMap()
+		for i := 0; i < n; i++ {
+			m = m.Assoc(i, i*2)
+		}
+
+		if m.Count() != n {
+			t.Fatalf("%d assocs: count is %d", n, m.Count())
+		}
+		for i := 0; i < n; i++ {
+			if v, ok := m.Find(i); !ok || v != i*2 {
+				t.Fatalf("%d assocs: find %d gave %v, %v", n, i, v, ok)
+			}
+		}
+		if _, ok := m.Find(n); ok {
+			t.Errorf("%d assocs: found %d", n, n)
+		}
+
+		odd := m
+		for i := 0; i < n; i += 2 {
+			odd = odd.Dissoc(i)
+		}
+		if odd.Count() != n/2 || m.Count() != n {
+			t.Errorf("%d assocs: dissoc left %d, the original %d", n, odd.Count(), m.Count())
+		}
+		for k := range odd.All() {
+			if k.(int)%2 == 0 {
+				t.Errorf("%d assocs: %v wasn't dissoc'ed", n, k)
+			}
+		}
+	}
+}
+
+func TestHashMapKeys(t *testing.T) {
+	// 1 and 1<<32 hash the same, ending up in a collision node
+	collision := 1 << 32
+
+	tests := []struct {
+		name  string
+		m     *PersistentHashMap
+		key   Any
+		value Any
+		count int
+	}{
+		{"replace", HashMap(1, "a").Assoc(1, "b"), 1, "b", 1},
+		{"equal numbers", HashMap(1, "a").Assoc(1.0, "b"), big.NewInt(1), "b", 1},
+		{"string not keyword", HashMap("a", 1).Assoc(Keyword("a"), 2), "a", 1, 2},
+		{"vector key", HashMap(Vector(1, 2), "v"), []Any{1, 2}, "v", 1},
+		{"nil key", HashMap(nil, "n", 0, "z"), nil, "n", 2},
+		{"collision", HashMap(1, "a", collision, "b"), collision, "b", 2},
+		{"collision dissoc", HashMap(1, "a", collision, "b").Dissoc(1), collision, "b", 1},
+	}
+
+	for _, test := range tests {
+		if v, _ := test.m.Find(test.key); v != test.value || test.m.Count() != test.count {
+			t.Errorf("%s: got %v and count %d, want %v and %d", test.name, v, test.m.Count(), test.value, test.count)
+		}
+	}
+}
+
+func TestSortedMap(t *testing.T) {
+	const n = 1000
+
+	m := SortedMap()
+	for i := 0; i < n; i++ {
+		// a permutation of 0..n-1
+		k := i * 7919 % n
+		m = m.Assoc(k, -k)
+	}
+
+	if m.Count() != n {
+		t.Fatalf("count is %d", m.Count())
+	}
+	if depth := m.root.depth(); float64(depth) > 1.45*math.Log2(n+2) {
+		t.Errorf("tree of %d keys is %d deep", n, depth)
+	}
+
+	for i := 0; i < n; i += 3 {
+		m = m.Dissoc(i)
+	}
+
+	prev := -1
+	count := 0
+	for k, v := range m.All() {
+		if k.(int) <= prev || k.(int)%3 == 0 || v != -k.(int) {
+			t.Fatalf("%v %v after %v", k, v, prev)
+		}
+		prev = k.(int)
+		count++
+	}
+	if count != m.Count() || count != n-(n+2)/3 {
+		t.Errorf("iterated %d entries, count is %d", count, m.Count())
+	}
+
+	if m.Dissoc(0) != m {
+		t.Errorf("dissoc of a missing key made a new map")
+	}
+}
+
+func TestSortedSet(t *testing.T) {
+	s := SortedSet(3, 1.5, 2, 1, 3.0, 0)
+
+	got := []Any{}
+	for x := range s.All() {
+		got = append(got, x)
+	}
+
+	if !Equal(got, []Any{0, 1, 1.5, 2, 3}) {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestTransient(t *testing.T) {
+	v := Vector(1, 2, 3)
+	tv := v.Transient()
+	tv.Assoc(0, 9).Conj(4)
+	if v.Nth(0) != 1 || v.Count() != 3 {
+		t.Errorf("transient changed its vector to %v", v)
+	}
+	if p := tv.Persistent(); !Equal(p, Vector(9, 2, 3, 4)) {
+		t.Errorf("transient vector built %v", p)
+	}
+
+	big := Vector().Transient()
+	for i := 0; i < 2000; i++ {
+		big.Conj(i)
+	}
+	big.Assoc(1500, -1)
+	if p := big.Persistent(); p.Count() != 2000 || p.Nth(1999) != 1999 || p.Nth(1500) != -1 {
+		t.Errorf("transient vector of 2000 is wrong")
+	}
+
+	m := HashMap(1, 1, 2, 2)
+	tm := m.Transient()
+	tm.Assoc(3, 3).Dissoc(1)
+	if m.Count() != 2 || !IsContains(m, 1) {
+		t.Errorf("transient changed its map to %v", m)
+	}
+	if p := tm.Persistent(); !Equal(p, HashMap(2, 2, 3, 3)) {
+		t.Errorf("transient map built %v", p)
+	}
+
+	s := Transient(HashSet(1))
+	s = ConjBang(s, 2, 3)
+	s = DisjBang(s, 1)
+	if p := PersistentBang(s); !Equal(p, HashSet(2, 3)) {
+		t.Errorf("transient set built %v", p)
+	}
+
+	tests := []struct {
+		name string
+		f    func()
+	}{
+		{"vector", func() { tv.Conj(5) }},
+		{"map", func() { tm.Assoc(4, 4) }},
+		{"set", func() { ConjBang(s, 4) }},
+	}
+
+	for _, test := range tests {
+		if recovered(test.f) == nil {
+			t.Errorf("%s transient used after persistent! didn't panic", test.name)
+		}
+	}
+}
+
+func TestCollFuncs(t *testing.T) {
+	a, b := Keyword("a"), Keyword("b")
+
+	tests := []struct {
+		name      string
+		got, want Any
+	}{
+		{"conj vector", Conj(Vector(1), 2, 3), Vector(1, 2, 3)},
+		{"conj nil", Conj(nil, 1), Vector(1)},
+		{"conj set", Conj(HashSet(1), 1, 2), HashSet(1, 2)},
+		{"conj map", Conj(HashMap(), Vector(a, 1)), HashMap(a, 1)},
+		{"assoc vector", Assoc(Vector(1, 2), 0, 5, 2, 6), Vector(5, 2, 6)},
+		{"assoc nil", Assoc(nil, a, 1), HashMap(a, 1)},
+		{"assoc sorted", Assoc(SortedMap(b, 2), a, 1), SortedMap(a, 1, b, 2)},
+		{"dissoc", Dissoc(HashMap(a, 1, b, 2), a, Keyword("c")), HashMap(b, 2)},
+		{"disj", Disj(SortedSet(1, 2, 3), 2), SortedSet(1, 3)},
+		{"get map", Get(a, HashMap(a, 1)), 1},
+		{"get missing", Get(b, HashMap(a, 1)), nil},
+		{"get default", Get(b, 0, SortedMap(a, 1)), 0},
+		{"get vector", Get(1, Vector(5, 6)), 6},
+		{"get subvector", Get(1, -1, Vector(5, 6, 7)), Vector(6, 7)},
+		{"get set", Get(2, HashSet(1, 2)), 2},
+		{"count map", Count(HashMap(a, 1, b, 2)), 2},
+		{"count set", Count(HashSet(1, 1.0, 2)), 2},
+		{"contains", IsContains(HashMap(a, nil), a), true},
+		{"contains index", IsContains(Vector(1), 1), false},
+	}
+
+	for _, test := range tests {
+		if !Equal(test.got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, test.got, test.want)
+		}
+	}
+}
+
+const benchSize = 10000
+
+func BenchmarkSliceAppend(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		var xs []Any
+		for i := 0; i < benchSize; i++ {
+			xs = append(xs, i)
+		}
+	}
+}
+
+func BenchmarkVectorConj(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		v := Vector()
+		for i := 0; i < benchSize; i++ {
+			v = v.Conj(i)
+		}
+	}
+}
+
+func BenchmarkTransientVectorConj(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		t := Vector().Transient()
+		for i := 0; i < benchSize; i++ {
+			t.Conj(i)
+		}
+		t.Persistent()
+	}
+}
+
+func BenchmarkSliceIndex(b *testing.B) {
+	xs := make([]Any, benchSize)
+	for i := range xs {
+		xs[i] = i
+	}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for i := 0; i < benchSize; i++ {
+			_ = xs[i]
+		}
+	}
+}
+
+func BenchmarkVectorNth(b *testing.B) {
+	t := Vector().Transient()
+	for i := 0; i < benchSize; i++ {
+		t.Conj(i)
+	}
+	v := t.Persistent()
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for i := 0; i < benchSize; i++ {
+			_ = v.Nth(i)
+		}
+	}
+}
+
+// copying the slice is what it takes to change an element of a
+// shared []Any safely
+func BenchmarkSliceCopySet(b *testing.B) {
+	xs := make([]Any, benchSize)
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		ys := make([]Any, len(xs))
+		copy(ys, xs)
+		ys[benchSize/2] = 1
+	}
+}
+
+func BenchmarkVectorAssoc(b *testing.B) {
+	v := Vector(make([]Any, benchSize)...)
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		v.Assoc(benchSize/2, 1)
+	}
+}
+
+func BenchmarkGoMapSet(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		m := map[Any]Any{}
+		for i := 0; i < benchSize; i++ {
+			m[i] = i
+		}
+	}
+}
+
+func BenchmarkHashMapAssoc(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		m := HashMap()
+		for i := 0; i < benchSize; i++ {
+			m = m.Assoc(i, i)
+		}
+	}
+}
+
+func BenchmarkTransientHashMapAssoc(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		t := HashMap().Transient()
+		for i := 0; i < benchSize; i++ {
+			t.Assoc(i, i)
+		}
+		t.Persistent()
+	}
+}
+
+func BenchmarkSortedMapAssoc(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		m := SortedMap()
+		for i := 0; i < benchSize; i++ {
+			m = m.Assoc(i, i)
+		}
+	}
+}
+
+func BenchmarkGoMapLookup(b *testing.B) {
+	m := map[Any]Any{}
+	for i := 0; i < benchSize; i++ {
+		m[i] = i
+	}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for i := 0; i < benchSize; i++ {
+			_ = m[i]
+		}
+	}
+}
+
+func BenchmarkHashMapFind(b *testing.B) {
+	t := HashMap().Transient()
+	for i := 0; i < benchSize; i++ {
+		t.Assoc(i, i)
+	}
+	m := t.Persistent()
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for i := 0; i < benchSize; i++ {
+			m.Find(i)
+		}
+	}
+}
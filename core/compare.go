@@ -0,0 +1,235 @@
+package core
+
+import (
+	"fmt"
+	"iter"
+	"math/big"
+	"reflect"
+	"strings"
+)
+
+// Equal reports whether a and b have the same value: numbers of any
+// kind are compared by value, vectors, maps, sets and structs
+// element by element. Unlike ==, NaN equals NaN, as Compare orders
+// it next to itself.
+func Equal(a, b Any) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	if isNumber(a) && isNumber(b) {
+		return equalNumbers(a, b)
+	}
+
+	switch x := a.(type) {
+	case string:
+		if y, ok := b.(string); ok {
+			return x == y
+		}
+	case Keyword:
+		if y, ok := b.(Keyword); ok {
+			return x == y
+		}
+	}
+
+	if sa, ok := a.(setLike); ok {
+		sb, ok := b.(setLike)
+		return ok && sa.Count() == sb.Count() && containsAll(sb, sa.All())
+	}
+
+	if ia, ok := asIndexed(a); ok {
+		ib, ok := asIndexed(b)
+		if !ok || ia.Count() != ib.Count() {
+			return false
+		}
+
+		for i := 0; i < ia.Count(); i++ {
+			if !Equal(ia.Nth(i), ib.Nth(i)) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if ma, ok := asAssociative(a); ok {
+		mb, ok := asAssociative(b)
+		if !ok || ma.Count() != mb.Count() {
+			return false
+		}
+
+		for k, v := range ma.All() {
+			if other, ok := mb.Find(k); !ok || !Equal(v, other) {
+				return false
+			}
+		}
+		return true
+	}
+
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+
+	switch {
+	case va.Type() != vb.Type():
+		return false
+
+	case va.Kind() == reflect.Struct:
+		for i := 0; i < va.NumField(); i++ {
+			if !va.Type().Field(i).IsExported() {
+				// can't get at its value, but DeepEqual can
+				return reflect.DeepEqual(a, b)
+			}
+
+			if !Equal(va.Field(i).Interface(), vb.Field(i).Interface()) {
+				return false
+			}
+		}
+		return true
+
+	case va.Comparable():
+		return a == b
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+// Compare returns -1, 0 or 1 if a is less than, equal to or greater
+// than b. nil comes first, numbers are compared by value (NaN after
+// all of them), strings (and keywords) lexicographically, false
+// before true, vectors by length first and then element by element.
+// Other types can be compared if they have a Compare(other T) int
+// method, like time.Time.
+func Compare(a, b Any) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+
+	if isNumber(a) && isNumber(b) {
+		return compareNumbers(a, b)
+	}
+
+	if ia, ok := asIndexed(a); ok {
+		if ib, ok := asIndexed(b); ok {
+			if n := cmpOrdered(ia.Count(), ib.Count()); n != 0 {
+				return n
+			}
+
+			for i := 0; i < ia.Count(); i++ {
+				if n := Compare(ia.Nth(i), ib.Nth(i)); n != 0 {
+					return n
+				}
+			}
+			return 0
+		}
+	}
+
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+
+	switch {
+	case va.Kind() == reflect.String && vb.Kind() == reflect.String && va.Type() == vb.Type():
+		return strings.Compare(va.String(), vb.String())
+
+	case va.Kind() == reflect.Bool && vb.Kind() == reflect.Bool:
+		return compareBools(va.Bool(), vb.Bool())
+
+	case va.Type() == vb.Type():
+		if cmp := va.MethodByName("Compare"); cmp.IsValid() {
+			typ := cmp.Type()
+			if typ.NumIn() == 1 && typ.In(0) == vb.Type() && typ.NumOut() == 1 && typ.Out(0).Kind() == reflect.Int {
+				return int(cmp.Call([]reflect.Value{vb})[0].Int())
+			}
+		}
+	}
+
+	panic(fmt.Sprintf("can't compare %T with %T", a, b))
+}
+
+func compareBools(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case b:
+		return -1
+	}
+
+	return 1
+}
+
+func isNumber(x Any) bool {
+	switch x.(type) {
+	case int, float64, *big.Int, *big.Rat, complex128:
+		return true
+	}
+
+	k := reflect.ValueOf(x).Kind()
+	return reflect.Int <= k && k <= reflect.Complex128
+}
+
+// asIndexed returns a vector, slice or array as an indexed
+func asIndexed(x Any) (indexed, bool) {
+	if c, ok := x.(indexed); ok {
+		return c, true
+	}
+
+	switch v := reflect.ValueOf(x); v.Kind() {
+	case reflect.Slice, reflect.Array:
+		return reflectIndexed{v}, true
+	}
+
+	return nil, false
+}
+
+type reflectIndexed struct {
+	v reflect.Value
+}
+
+func (r reflectIndexed) Count() int {
+	return r.v.Len()
+}
+
+func (r reflectIndexed) Nth(i int) Any {
+	return r.v.Index(i).Interface()
+}
+
+// asAssociative returns a persistent or Go map as an associative
+func asAssociative(x Any) (associative, bool) {
+	if c, ok := x.(associative); ok {
+		return c, true
+	}
+
+	if !IsMap(x) {
+		return nil, false
+	}
+
+	return reflectMap{x}, true
+}
+
+type reflectMap struct {
+	m Any
+}
+
+func (r reflectMap) Count() int {
+	return reflect.ValueOf(r.m).Len()
+}
+
+func (r reflectMap) Find(key Any) (Any, bool) {
+	return Find(r.m, key)
+}
+
+func (r reflectMap) All() iter.Seq2[Any, Any] {
+	return func(yield func(Any, Any) bool) {
+		rangeReflect(r.m, yield)
+	}
+}
+
+func containsAll(s setLike, elems iter.Seq[Any]) bool {
+	for x := range elems {
+		if !s.Contains(x) {
+			return false
+		}
+	}
+	return true
+}
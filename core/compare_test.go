@@ -0,0 +1,117 @@
+package core
+
+import (
+	"math"
+	"math/big"
+	"testing"
+	"time"
+)
+
+type point struct {
+	X, Y Any
+}
+
+func TestEQ(t *testing.T) {
+	nan := math.NaN()
+
+	tests := []struct {
+		a, b Any
+		want bool
+	}{
+		{1, 1.0, true},
+		{1, big.NewInt(1), true},
+		{big.NewRat(1, 2), 0.5, true},
+		{1, 2, false},
+		{int8(3), 3, true},
+		{nil, nil, true},
+		{nil, 0, false},
+		{"a", "a", true},
+		{"a", Keyword("a"), false},
+		{Vector(1, 2), []Any{1, 2.0}, true},
+		{Vector(1), Vector(1, 2), false},
+		{[]Any{1, 2}, []int{1, 2}, true},
+		{HashMap(Keyword("a"), 1), SortedMap(Keyword("a"), 1), true},
+		{HashMap(Keyword("a"), 1), map[Any]Any{Keyword("a"): 2}, false},
+		{HashSet(1, 2), SortedSet(2, 1), true},
+		{HashSet(1), Vector(1), false},
+		{nan, nan, true},
+		{nan, 1, false},
+		{nan, math.Inf(1), false},
+		{point{1, 2}, point{1.0, 2}, true},
+		{point{1, 2}, point{1, 3}, false},
+	}
+
+	for _, test := range tests {
+		if got := EQ(test.a, test.b); got != test.want {
+			t.Errorf("(= %v %v) is %v", test.a, test.b, got)
+		}
+		if test.want && Hash(test.a) != Hash(test.b) {
+			t.Errorf("%v and %v are equal, but hash differently", test.a, test.b)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	now := time.Now()
+	nan := math.NaN()
+
+	tests := []struct {
+		a, b Any
+		want int
+	}{
+		{1, 2, -1},
+		{2, 1.5, 1},
+		{big.NewRat(1, 3), 0.3, 1},
+		{big.NewInt(1), 1.0, 0},
+		{nil, 1, -1},
+		{1, nil, 1},
+		{"a", "b", -1},
+		{Keyword("b"), Keyword("a"), 1},
+		{false, true, -1},
+		{Vector(1, 2), Vector(3), 1},
+		{Vector(1, 2), Vector(1, 3), -1},
+		{Vector(1, 2), []Any{1, 2}, 0},
+		{now, now.Add(time.Second), -1},
+		// NaN is after every number, and equal to itself
+		{nan, 1, 1},
+		{1, nan, -1},
+		{nan, math.Inf(1), 1},
+		{math.Inf(-1), nan, -1},
+		{nan, nan, 0},
+	}
+
+	for _, test := range tests {
+		if got := Compare(test.a, test.b); got != test.want {
+			t.Errorf("(compare %v %v) is %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+
+	if recovered(func() { Compare("a", 1) }) == nil {
+		t.Error("comparing a string with a number didn't panic")
+	}
+}
+
+func TestCompareChain(t *testing.T) {
+	nan := math.NaN()
+
+	tests := []struct {
+		name string
+		got  bool
+		want bool
+	}{
+		{"(< 1 2 3)", LT(1, 2, 3), true},
+		{"(< 1 3 2)", LT(1, 3, 2), false},
+		{"(<= 1 1 2.5)", LTEQ(1, 1, 2.5), true},
+		{"(> 3 2 1/2)", GT(3, 2, big.NewRat(1, 2)), true},
+		{"(>= 2 2 3)", GTEQ(2, 2, 3), false},
+		{"(< \"a\" \"b\")", LT("a", "b"), true},
+		{"(< 1 NaN)", LT(1, nan), true},
+		{"(> 1 NaN)", GT(1, nan), false},
+	}
+
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("%s is %v", test.name, test.got)
+		}
+	}
+}
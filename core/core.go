@@ -4,107 +4,21 @@ import "fmt"
 
 type Any interface{}
 
-func MOD(a, b Any) int {
-    var n, m int
-
-    if isInt(a) {
-        n = a.(int)
-    } else if isFloat(a) {
-        n = int(a.(float64))
-    } else {
-        panic("need int/float argument to mod!")
-    }
-
-    if isInt(b) {
-        m = b.(int)
-    } else if isFloat(a) {
-        m = int(b.(float64))
-    } else {
-        panic("need int/float argument to mod!")
-    }
-
-    return n % m
-}
-
-func ADD(args ...Any) float64 {
-    var sum float64 = 0
-
-    for i := 0; i < len(args); i++ {
-        switch n := args[i]; {
-        case isInt(n):
-            sum += float64(n.(int))
-        case isFloat(n):
-            sum += n.(float64)
-        }
-    }
-
-    return sum
-}
-
-func SUB(args ...Any) float64 {
-    var result float64
-    if isInt(args[0]) {
-        result = float64(args[0].(int))
-    } else if isFloat(args[0]) {
-        result = args[0].(float64)
-    } else {
-        panic("need int/float for SUB")
-    }
-
-    for i := 1; i < len(args); i++ {
-        switch n := args[i]; {
-        case isInt(n):
-            result -= float64(n.(int))
-        case isFloat(n):
-            result -= n.(float64)
-        }
-    }
-
-    return result
+func LT(args ...Any) bool {
+    return compareChain(args, func(n int) bool { return n < 0 })
 }
 
-func MUL(args ...Any) float64 {
-    var prod float64 = 1
-
-    for i := 0; i < len(args); i++ {
-        switch n := args[i]; {
-        case isInt(n):
-            prod *= float64(n.(int))
-        case isFloat(n):
-            prod *= n.(float64)
-        }
-    }
-
-    return prod
+func GT(args ...Any) bool {
+    return compareChain(args, func(n int) bool { return n > 0 })
 }
-func DIV() {}
 
-// TODO: can only compare ints and slice lens for now.
-func LT(args ...Any) bool {
+func EQ(args ...Any) bool {
     if len(args) < 2 {
         panic("can't compare less than 2 values!")
     }
 
     for i := 0; i < len(args)-1; i++ {
-        var n float64
-        if isInt(args[i]) {
-            n = float64(args[i].(int))
-        } else if isFloat(args[i]) {
-            n = args[i].(float64)
-        } else {
-            panic("you can't compare that!")
-        }
-
-        var m float64
-        if isInt(args[i+1]) {
-            m = float64(args[i+1].(int))
-        } else if isFloat(args[i+1]) {
-            m = args[i+1].(float64)
-        } else {
-            panic("you can't compare that!")
-        }
-
-        if n >= m {
+        if !Equal(args[i], args[i+1]) {
             return false
         }
     }
@@ -112,32 +26,25 @@ func LT(args ...Any) bool {
     return true
 }
 
-// TODO: can only compare ints and slice lens for now.
-func GT(args ...Any) bool {
+// greater than or equal
+func GTEQ(args ...Any) bool {
+    return compareChain(args, func(n int) bool { return n >= 0 })
+}
+
+// less than or equal
+func LTEQ(args ...Any) bool {
+    return compareChain(args, func(n int) bool { return n <= 0 })
+}
+
+// compareChain reports whether every argument compares to the next
+// one as expected, i.e. (< 1 2 3)
+func compareChain(args []Any, expected func(int) bool) bool {
     if len(args) < 2 {
         panic("can't compare less than 2 values!")
     }
 
     for i := 0; i < len(args)-1; i++ {
-        var n float64
-        if isInt(args[i]) {
-            n = float64(args[i].(int))
-        } else if isFloat(args[i]) {
-            n = args[i].(float64)
-        } else {
-            panic("you can't compare that!")
-        }
-
-        var m float64
-        if isInt(args[i+1]) {
-            m = float64(args[i+1].(int))
-        } else if isFloat(args[i+1]) {
-            m = args[i+1].(float64)
-        } else {
-            panic("you can't compare that!")
-        }
-
-        if n <= m {
+        if !expected(Compare(args[i], args[i+1])) {
             return false
         }
     }
@@ -145,69 +52,50 @@ func GT(args ...Any) bool {
     return true
 }
 
-func EQ(args ...Any) bool {
-    if len(args) < 2 {
-        panic("can't compare less than 2 values!")
+func Get(args ...Any) Any {
+    if len(args) != 2 && len(args) != 3 {
+        panic(fmt.Sprintf("get needs 2 or 3 arguments %d given.", len(args)))
     }
 
-    for i := 0; i < len(args)-1; i++ {
-        var n float64
-        if isInt(args[i]) {
-            n = float64(args[i].(int))
-        } else if isFloat(args[i]) {
-            n = args[i].(float64)
-        } else {
-            panic("you can't compare that!")
+    switch c := args[len(args)-1].(type) {
+    case setLike:
+        // (get x s) returns x, if it is an element of s
+        if c.Contains(args[0]) {
+            return args[0]
+        } else if len(args) == 3 {
+            return args[1]
         }
 
-        var m float64
-        if isInt(args[i+1]) {
-            m = float64(args[i+1].(int))
-        } else if isFloat(args[i+1]) {
-            m = args[i+1].(float64)
-        } else {
-            panic("you can't compare that!")
-        }
+        return nil
 
-        if n != m {
-            return false
+    case associative:
+        if v, ok := c.Find(args[0]); ok || len(args) == 2 {
+            return v
         }
-    }
 
-    return true
-}
+        return args[1]
 
-// greater than or equal
-func GTEQ(args ...Any) bool {
-    if GT(args...) || EQ(args...) {
-        return true
-    }
+    case *PersistentVector:
+        if len(args) == 2 {
+            return c.Nth(toIndex(args[0]))
+        }
 
-    return false
-}
+        // (get start end v) is the subvector from start to end
+        end := toIndex(args[1])
+        if end == -1 {
+            end = c.Count()
+        }
 
-// less than or equal
-func LTEQ(args ...Any) bool {
-    if LT(args...) || EQ(args...) {
-        return true
+        return Vector(c.Slice()[toIndex(args[0]):end]...)
     }
 
-    return false
-}
-
-func isFloat(n Any) bool {
-    _, ok := n.(float64)
-    return ok
-}
-
-func isInt(n Any) bool {
-    _, ok := n.(int)
-    return ok
-}
+    if m, ok := args[len(args)-1].(map[Any]Any); ok {
+        // (get key m) or (get key default m)
+        if v, ok := m[args[0]]; ok || len(args) == 2 {
+            return v
+        }
 
-func Get(args ...Any) Any {
-    if len(args) != 2 && len(args) != 3 {
-        panic(fmt.Sprintf("get needs 2 or 3 arguments %d given.", len(args)))
+        return args[1]
     }
 
     if len(args) == 2 {
@@ -216,7 +104,7 @@ func Get(args ...Any) Any {
         } else if a, ok := args[1].(string); ok {
             return a[args[0].(int)]
         } else {
-            panic("arguments to get must include slice/vector/string")
+            panic("arguments to get must include slice/vector/string/map")
         }
     } else {
         if a, ok := args[2].([]Any); ok {
@@ -232,7 +120,7 @@ func Get(args ...Any) Any {
 
             return a[args[0].(int):args[1].(int)]
         } else {
-            panic("arguments to get must include slice/vector/string")
+            panic("arguments to get must include slice/vector/string/map")
         }
     }
 }
\ No newline at end of file
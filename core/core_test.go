@@ -0,0 +1,25 @@
+package core
+
+import "testing"
+
+func TestGetMap(t *testing.T) {
+	m := map[Any]Any{Keyword("a"): 1, "b": nil}
+
+	tests := []struct {
+		args []Any
+		want Any
+	}{
+		{[]Any{Keyword("a"), m}, 1},
+		{[]Any{Keyword("c"), m}, nil},
+		{[]Any{Keyword("c"), 2, m}, 2},
+		{[]Any{"b", 2, m}, nil},
+		{[]Any{1, []Any{1, 2}}, 2},
+		{[]Any{1, "abc"}, uint8('b')},
+	}
+
+	for _, test := range tests {
+		if got := Get(test.args...); got != test.want {
+			t.Errorf("Get(%v) = %v, want %v", test.args, got, test.want)
+		}
+	}
+}
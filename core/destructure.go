@@ -0,0 +1,129 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Nth returns the i-th element of a vector, slice, array or string
+// being destructured, it panics when coll is shorter than that.
+func Nth(coll Any, i int) Any {
+	switch c := coll.(type) {
+	case []Any:
+		if i < len(c) {
+			return c[i]
+		}
+	case string:
+		if r := []rune(c); i < len(r) {
+			return r[i]
+		}
+	case indexed:
+		if i < c.Count() {
+			return c.Nth(i)
+		}
+	default:
+		v := sequential(coll)
+		if i < v.Len() {
+			return v.Index(i).Interface()
+		}
+	}
+
+	panic(fmt.Sprintf("can't destructure element %d from %T of length %d", i, coll, Count(coll)))
+}
+
+// NthRest returns the elements from the i-th on as a vector, these
+// are bound to the identifier after & in a destructuring.
+func NthRest(coll Any, i int) []Any {
+	n := Count(coll)
+	if i > n {
+		panic(fmt.Sprintf("can't destructure element %d from %T of length %d", i, coll, n))
+	}
+
+	rest := make([]Any, 0, n-i)
+	for ; i < n; i++ {
+		rest = append(rest, Nth(coll, i))
+	}
+
+	return rest
+}
+
+// Count returns the number of elements in a vector, string, map or
+// set
+func Count(coll Any) int {
+	switch c := coll.(type) {
+	case nil:
+		return 0
+	case []Any:
+		return len(c)
+	case string:
+		return len([]rune(c))
+	case map[Any]Any:
+		return len(c)
+	case interface{ Count() int }:
+		return c.Count()
+	}
+
+	v := reflect.ValueOf(coll)
+	if v.Kind() == reflect.Map {
+		return v.Len()
+	}
+
+	return sequential(coll).Len()
+}
+
+func sequential(coll Any) reflect.Value {
+	v := reflect.ValueOf(coll)
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		return v
+	}
+
+	panic(fmt.Sprintf("can't destructure %T as a vector", coll))
+}
+
+// GetKey looks up key in the map (or the keyword arguments)
+// being destructured, missing keys give nil.
+func GetKey(m Any, key Any) Any {
+	switch c := m.(type) {
+	case nil:
+		return nil
+	case map[Any]Any:
+		return c[key]
+	case associative:
+		v, _ := c.Find(key)
+		return v
+	case []Any:
+		// keyword arguments, i.e. the rest of (f 1 :b 2 :c 3)
+		if len(c)%2 != 0 {
+			panic(fmt.Sprintf("keyword arguments need to come in pairs, got: %v", c))
+		}
+
+		for i := 0; i < len(c); i += 2 {
+			if c[i] == key {
+				return c[i+1]
+			}
+		}
+
+		return nil
+	}
+
+	v := reflect.ValueOf(m)
+	if v.Kind() != reflect.Map {
+		panic(fmt.Sprintf("can't destructure %T as a map", m))
+	}
+
+	k := reflect.ValueOf(key)
+	if !k.Type().AssignableTo(v.Type().Key()) {
+		if !k.Type().ConvertibleTo(v.Type().Key()) {
+			panic(fmt.Sprintf("can't look up %T key in %T", key, m))
+		}
+		k = k.Convert(v.Type().Key())
+	}
+
+	if val := v.MapIndex(k); val.IsValid() {
+		return val.Interface()
+	}
+
+	return nil
+}
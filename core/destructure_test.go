@@ -0,0 +1,105 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNth(t *testing.T) {
+	tests := []struct {
+		coll Any
+		i    int
+		want Any
+	}{
+		{[]Any{1, 2}, 1, 2},
+		{"héllo", 1, 'é'},
+		{[]string{"a", "b"}, 0, "a"},
+		{[2]int{3, 4}, 1, 4},
+	}
+
+	for _, test := range tests {
+		if got := Nth(test.coll, test.i); got != test.want {
+			t.Errorf("Nth(%v, %d) = %v, want %v", test.coll, test.i, got, test.want)
+		}
+	}
+}
+
+func TestNthRest(t *testing.T) {
+	if got := NthRest([]Any{1, 2, 3}, 1); !reflect.DeepEqual(got, []Any{2, 3}) {
+		t.Errorf("NthRest = %v, want [2 3]", got)
+	}
+
+	if got := NthRest([]int{1}, 1); len(got) != 0 {
+		t.Errorf("NthRest = %v, want []", got)
+	}
+}
+
+func TestCount(t *testing.T) {
+	tests := []struct {
+		coll Any
+		want int
+	}{
+		{nil, 0},
+		{[]Any{1, 2}, 2},
+		{"héllo", 5},
+		{map[Any]Any{1: 2}, 1},
+		{map[string]int{"a": 1, "b": 2}, 2},
+		{[]int{1, 2, 3}, 3},
+	}
+
+	for _, test := range tests {
+		if got := Count(test.coll); got != test.want {
+			t.Errorf("Count(%v) = %d, want %d", test.coll, got, test.want)
+		}
+	}
+}
+
+func TestGetKey(t *testing.T) {
+	tests := []struct {
+		m, key, want Any
+	}{
+		{nil, "a", nil},
+		{map[Any]Any{Keyword("a"): 1}, Keyword("a"), 1},
+		{map[Any]Any{}, Keyword("a"), nil},
+		{map[string]int{"a": 1}, "a", 1},
+		{map[Keyword]int{"a": 1}, "a", 1},
+		{map[string]int{}, "a", nil},
+		{[]Any{Keyword("a"), 1, Keyword("b"), 2}, Keyword("b"), 2},
+		{[]Any{Keyword("a"), 1}, Keyword("b"), nil},
+	}
+
+	for _, test := range tests {
+		if got := GetKey(test.m, test.key); got != test.want {
+			t.Errorf("GetKey(%v, %v) = %v, want %v", test.m, test.key, got, test.want)
+		}
+	}
+}
+
+func TestDestructuringPanics(t *testing.T) {
+	tests := []func(){
+		func() { Nth([]Any{1}, 1) },
+		func() { Nth(1, 0) },
+		func() { NthRest([]Any{1}, 2) },
+		func() { GetKey(1, "a") },
+		func() { GetKey(map[int]int{}, "a") },
+		func() { GetKey([]Any{Keyword("a")}, Keyword("a")) },
+	}
+
+	for i, test := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%d: didn't panic", i)
+				}
+			}()
+			test()
+		}()
+	}
+}
+
+func TestArityError(t *testing.T) {
+	var err error = ArityError(3)
+	if got := err.Error(); got != "wrong number of arguments (3) passed to fn" {
+		t.Errorf("got %q", got)
+	}
+}
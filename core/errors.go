@@ -0,0 +1,49 @@
+package core
+
+// errReturn carries an error raised by TryErr up to the CatchErr
+// deferred by the enclosing gisp function.
+type errReturn struct {
+	err error
+}
+
+func (e errReturn) Error() string {
+	return e.err.Error()
+}
+
+// TryErr unpacks a (value, error) pair. A non-nil error unwinds to the
+// enclosing function, which then returns the error as its result.
+func TryErr(v Any, err error) Any {
+	if err != nil {
+		panic(errReturn{err})
+	}
+
+	return v
+}
+
+// CatchErr has to be deferred by every function using TryErr, ret
+// being the function's named result.
+func CatchErr(ret *Any) {
+	if r := recover(); r != nil {
+		if e, ok := r.(errReturn); ok {
+			*ret = e.err
+			return
+		}
+
+		panic(r)
+	}
+}
+
+// Ok reports whether the second value of a two-value result signals
+// success, i.e. it's either a true bool or a nil error.
+func Ok(v Any) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case bool:
+		return v
+	case error:
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,54 @@
+package core
+
+// Comp composes the functions, right to left: ((comp f g) x) is
+// (f (g x)). Without functions it's the identity.
+func Comp(fns ...Any) func(...Any) Any {
+	return func(args ...Any) Any {
+		if len(fns) == 0 {
+			if len(args) == 0 {
+				return nil
+			}
+			return args[0]
+		}
+
+		ret := call(fns[len(fns)-1], args...)
+		for i := len(fns) - 2; i >= 0; i-- {
+			ret = call(fns[i], ret)
+		}
+
+		return ret
+	}
+}
+
+// Partial fixes the first arguments of f.
+func Partial(f Any, fixed ...Any) func(...Any) Any {
+	return func(args ...Any) Any {
+		return call(f, append(append([]Any{}, fixed...), args...)...)
+	}
+}
+
+// Juxt calls every function with the same arguments, returning the
+// vector of their results.
+func Juxt(fns ...Any) func(...Any) Any {
+	return func(args ...Any) Any {
+		ret := make([]Any, len(fns))
+		for i, f := range fns {
+			ret[i] = call(f, args...)
+		}
+
+		return Vector(ret...)
+	}
+}
+
+// Complement returns a function with the opposite truth value of f,
+// nil and false being the only false values.
+func Complement(f Any) func(...Any) Any {
+	return func(args ...Any) Any {
+		ret := call(f, args...)
+		return ret == nil || ret == false
+	}
+}
+
+func IsNil(x Any) bool {
+	return x == nil
+}
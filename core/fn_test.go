@@ -0,0 +1,59 @@
+package core
+
+import "testing"
+
+func inc(x Any) Any    { return x.(int) + 1 }
+func double(x Any) Any { return x.(int) * 2 }
+
+func TestComp(t *testing.T) {
+	if got := Comp(inc, double)(3); got != 7 {
+		t.Errorf("(comp inc double) 3 = %v, want 7", got)
+	}
+
+	if got := Comp()(3); got != 3 {
+		t.Errorf("(comp) 3 = %v, want 3", got)
+	}
+
+	add := func(a, b Any) Any { return a.(int) + b.(int) }
+	if got := Comp(inc, add)(1, 2); got != 4 {
+		t.Errorf("(comp inc +) 1 2 = %v, want 4", got)
+	}
+}
+
+func TestPartial(t *testing.T) {
+	sub := func(a, b Any) Any { return a.(int) - b.(int) }
+	if got := Partial(sub, 10)(3); got != 7 {
+		t.Errorf("(partial - 10) 3 = %v, want 7", got)
+	}
+}
+
+func TestJuxt(t *testing.T) {
+	if got, ok := Juxt(inc, double)(3).(*PersistentVector); !ok || !Equal(got, Vector(4, 6)) {
+		t.Errorf("(juxt inc double) 3 = %v, want [4 6]", got)
+	}
+}
+
+func TestComplement(t *testing.T) {
+	tests := []struct {
+		ret  Any
+		want bool
+	}{
+		{nil, true},
+		{false, true},
+		{true, false},
+		{0, false},
+	}
+
+	for _, test := range tests {
+		f := func() Any { return test.ret }
+		if got := Complement(f)(); got != test.want {
+			t.Errorf("(complement (fn [] %v)) = %v, want %v", test.ret, got, test.want)
+		}
+	}
+}
+
+func TestIsNil(t *testing.T) {
+	if !IsNil(nil) || IsNil(false) {
+		t.Error("only nil is nil")
+	}
+}
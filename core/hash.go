@@ -0,0 +1,147 @@
+package core
+
+import (
+	"math"
+	"math/big"
+	"reflect"
+)
+
+// Hash returns the hash of x used by hash maps and sets. Values that
+// are Equal hash the same, whatever their type: 1, 1.0 and 1N, or a
+// vector and a []Any of the same elements.
+func Hash(x Any) uint32 {
+	switch v := x.(type) {
+	case nil:
+		return 0
+	case int:
+		return hashInt(int64(v))
+	case string:
+		return hashString(v)
+	case Keyword:
+		// keywords never equal strings, but keep them apart anyway
+		return hashString(string(v)) ^ 0x9e3779b9
+	case bool:
+		if v {
+			return 1231
+		}
+		return 1237
+	}
+
+	if isNumber(x) {
+		return hashNumber(x)
+	}
+
+	switch c := x.(type) {
+	case indexed:
+		h := uint32(1)
+		for i := 0; i < c.Count(); i++ {
+			h = 31*h + Hash(c.Nth(i))
+		}
+		return h
+	case associative:
+		return hashEntries(c.All())
+	case setLike:
+		return hashUnordered(c.All())
+	}
+
+	return hashReflect(reflect.ValueOf(x))
+}
+
+func hashReflect(v reflect.Value) uint32 {
+	switch v.Kind() {
+	case reflect.String:
+		return hashString(v.String())
+	case reflect.Bool:
+		return Hash(v.Bool())
+	case reflect.Slice, reflect.Array:
+		h := uint32(1)
+		for i := 0; i < v.Len(); i++ {
+			h = 31*h + Hash(v.Index(i).Interface())
+		}
+		return h
+	case reflect.Map:
+		var h uint32
+		iter := v.MapRange()
+		for iter.Next() {
+			h += Hash(iter.Key().Interface()) ^ Hash(iter.Value().Interface())
+		}
+		return h
+	case reflect.Struct:
+		h := hashString(v.Type().String())
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				h = 31*h + Hash(v.Field(i).Interface())
+			}
+		}
+		return h
+	case reflect.Pointer, reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		return hashInt(int64(v.Pointer()))
+	}
+
+	return 0
+}
+
+// Only ints and whole floats in the int range can be equal across
+// kinds, everything else is hashed by its float64 value, as that is
+// what it is compared as.
+func hashNumber(x Any) uint32 {
+	switch n, _ := toNumber(x); n := n.(type) {
+	case int:
+		return hashInt(int64(n))
+	case *big.Int:
+		if n.IsInt64() {
+			return hashInt(n.Int64())
+		}
+		f, _ := new(big.Float).SetInt(n).Float64()
+		return hashFloat(f)
+	case *big.Rat:
+		if n.IsInt() {
+			return hashNumber(n.Num())
+		}
+		f, _ := n.Float64()
+		return hashFloat(f)
+	case float64:
+		return hashFloat(n)
+	case complex128:
+		if imag(n) == 0 {
+			return hashFloat(real(n))
+		}
+		return 31*hashFloat(real(n)) + hashFloat(imag(n))
+	}
+
+	return 0
+}
+
+func hashFloat(f float64) uint32 {
+	if math.IsNaN(f) {
+		// all NaNs are Equal, whatever their bits
+		return 0x7ff80000
+	}
+
+	if f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+		return hashInt(int64(f))
+	}
+
+	return hashInt(int64(math.Float64bits(f)))
+}
+
+// the finalizer of murmur3, spreading the bits of small ints
+func hashInt(i int64) uint32 {
+	h := uint32(i) ^ uint32(i>>32)
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
+
+// FNV-1a
+func hashString(s string) uint32 {
+	h := uint32(2166136261)
+	for i := 0; i < len(s); i++ {
+		h ^= uint32(s[i])
+		h *= 16777619
+	}
+	return h
+}
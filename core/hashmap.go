@@ -0,0 +1,387 @@
+package core
+
+import (
+	"fmt"
+	"iter"
+	"math/bits"
+	"slices"
+	"strings"
+)
+
+// hnode is a node of a hash array mapped trie: the bitmap has a bit
+// set for every 5 bit hash fragment present, the entries are in the
+// order of those bits. Keys whose hashes are entirely equal end up
+// in a collision node, searched linearly.
+type hnode struct {
+	edit      *owner
+	bitmap    uint32
+	entries   []hentry
+	collision bool
+}
+
+// hentry is either a key and value, or a sub-trie
+type hentry struct {
+	key, value Any
+	hash       uint32
+	node       *hnode
+}
+
+// the last level uses the remaining 2 bits of the hash
+const maxShift = 30
+
+func bitpos(hash uint32, shift uint) uint32 {
+	return 1 << ((hash >> shift) & trieMask)
+}
+
+func (n *hnode) index(bit uint32) int {
+	return bits.OnesCount32(n.bitmap & (bit - 1))
+}
+
+// editable returns n, if edit owns it, or a copy owned by edit with
+// room for one more entry.
+func (n *hnode) editable(edit *owner) *hnode {
+	if edit != nil && n.edit == edit {
+		return n
+	}
+
+	entries := make([]hentry, len(n.entries), len(n.entries)+1)
+	copy(entries, n.entries)
+	return &hnode{edit: edit, bitmap: n.bitmap, entries: entries, collision: n.collision}
+}
+
+func (n *hnode) find(shift uint, hash uint32, key Any) (Any, bool) {
+	for n != nil {
+		if n.collision {
+			for _, e := range n.entries {
+				if Equal(e.key, key) {
+					return e.value, true
+				}
+			}
+			return nil, false
+		}
+
+		bit := bitpos(hash, shift)
+		if n.bitmap&bit == 0 {
+			return nil, false
+		}
+
+		e := n.entries[n.index(bit)]
+		if e.node == nil {
+			if Equal(e.key, key) {
+				return e.value, true
+			}
+			return nil, false
+		}
+
+		n, shift = e.node, shift+trieBits
+	}
+
+	return nil, false
+}
+
+func (n *hnode) assoc(edit *owner, shift uint, entry hentry, added *bool) *hnode {
+	if n == nil {
+		*added = true
+		return &hnode{edit: edit, bitmap: bitpos(entry.hash, shift), entries: []hentry{entry}}
+	}
+
+	if n.collision {
+		node := n.editable(edit)
+		for i, e := range n.entries {
+			if Equal(e.key, entry.key) {
+				node.entries[i].value = entry.value
+				return node
+			}
+		}
+
+		*added = true
+		node.entries = append(node.entries, entry)
+		return node
+	}
+
+	bit := bitpos(entry.hash, shift)
+	i := n.index(bit)
+
+	if n.bitmap&bit == 0 {
+		*added = true
+		node := n.editable(edit)
+		node.bitmap |= bit
+		node.entries = append(node.entries, hentry{})
+		copy(node.entries[i+1:], node.entries[i:])
+		node.entries[i] = entry
+		return node
+	}
+
+	e := n.entries[i]
+	switch {
+	case e.node != nil:
+		child := e.node.assoc(edit, shift+trieBits, entry, added)
+		if child == e.node {
+			return n
+		}
+
+		node := n.editable(edit)
+		node.entries[i].node = child
+		return node
+
+	case Equal(e.key, entry.key):
+		node := n.editable(edit)
+		node.entries[i].value = entry.value
+		return node
+	}
+
+	*added = true
+	node := n.editable(edit)
+	node.entries[i] = hentry{node: mergeEntries(edit, shift+trieBits, e, entry)}
+	return node
+}
+
+// mergeEntries returns a node holding two entries with different keys
+// which share the hash fragments up to shift.
+func mergeEntries(edit *owner, shift uint, a, b hentry) *hnode {
+	if shift > maxShift {
+		return &hnode{edit: edit, entries: []hentry{a, b}, collision: true}
+	}
+
+	bitA, bitB := bitpos(a.hash, shift), bitpos(b.hash, shift)
+	switch {
+	case bitA == bitB:
+		return &hnode{edit: edit, bitmap: bitA, entries: []hentry{{node: mergeEntries(edit, shift+trieBits, a, b)}}}
+	case bitA < bitB:
+		return &hnode{edit: edit, bitmap: bitA | bitB, entries: []hentry{a, b}}
+	}
+
+	return &hnode{edit: edit, bitmap: bitA | bitB, entries: []hentry{b, a}}
+}
+
+// dissoc returns the node without key, nil when it is left empty.
+func (n *hnode) dissoc(edit *owner, shift uint, hash uint32, key Any, removed *bool) *hnode {
+	if n == nil {
+		return nil
+	}
+
+	if n.collision {
+		for i, e := range n.entries {
+			if Equal(e.key, key) {
+				*removed = true
+				return n.without(edit, i, 0)
+			}
+		}
+		return n
+	}
+
+	bit := bitpos(hash, shift)
+	if n.bitmap&bit == 0 {
+		return n
+	}
+
+	i := n.index(bit)
+	e := n.entries[i]
+
+	if e.node == nil {
+		if !Equal(e.key, key) {
+			return n
+		}
+
+		*removed = true
+		return n.without(edit, i, bit)
+	}
+
+	child := e.node.dissoc(edit, shift+trieBits, hash, key, removed)
+	switch {
+	case child == e.node:
+		return n
+	case child == nil:
+		return n.without(edit, i, bit)
+	}
+
+	node := n.editable(edit)
+	if len(child.entries) == 1 && child.entries[0].node == nil {
+		// a single key moves up into this node
+		node.entries[i] = child.entries[0]
+	} else {
+		node.entries[i].node = child
+	}
+	return node
+}
+
+func (n *hnode) without(edit *owner, i int, bit uint32) *hnode {
+	if len(n.entries) == 1 {
+		return nil
+	}
+
+	node := n.editable(edit)
+	node.bitmap &^= bit
+	node.entries = slices.Delete(node.entries, i, i+1)
+	return node
+}
+
+func (n *hnode) each(yield func(Any, Any) bool) bool {
+	if n == nil {
+		return true
+	}
+
+	for _, e := range n.entries {
+		if e.node != nil {
+			if !e.node.each(yield) {
+				return false
+			}
+		} else if !yield(e.key, e.value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// PersistentHashMap is what {...} literals evaluate to, an immutable
+// map keyed by Equal values, a hash array mapped trie sharing its
+// structure with the maps it was made from.
+type PersistentHashMap struct {
+	count int
+	root  *hnode
+}
+
+var emptyHashMap = &PersistentHashMap{}
+
+// HashMap returns a persistent map of the keys and values in kvs,
+// later keys replacing earlier equal ones.
+func HashMap(kvs ...Any) *PersistentHashMap {
+	if len(kvs)%2 != 0 {
+		panic("hash-map needs an even number of keys and values!")
+	}
+
+	t := emptyHashMap.Transient()
+	for i := 0; i < len(kvs); i += 2 {
+		t.Assoc(kvs[i], kvs[i+1])
+	}
+
+	return t.Persistent()
+}
+
+func (m *PersistentHashMap) Count() int {
+	return m.count
+}
+
+// Find returns the value of key and whether m has it.
+func (m *PersistentHashMap) Find(key Any) (Any, bool) {
+	return m.root.find(0, Hash(key), key)
+}
+
+// Assoc returns a map with key set to value.
+func (m *PersistentHashMap) Assoc(key, value Any) *PersistentHashMap {
+	added := false
+	root := m.root.assoc(nil, 0, hentry{key: key, value: value, hash: Hash(key)}, &added)
+
+	count := m.count
+	if added {
+		count++
+	}
+
+	return &PersistentHashMap{count: count, root: root}
+}
+
+// Dissoc returns a map without key.
+func (m *PersistentHashMap) Dissoc(key Any) *PersistentHashMap {
+	removed := false
+	root := m.root.dissoc(nil, 0, Hash(key), key, &removed)
+	if !removed {
+		return m
+	}
+
+	return &PersistentHashMap{count: m.count - 1, root: root}
+}
+
+// All iterates over the keys and values of m, in no particular order.
+func (m *PersistentHashMap) All() iter.Seq2[Any, Any] {
+	return func(yield func(Any, Any) bool) {
+		m.root.each(yield)
+	}
+}
+
+func (m *PersistentHashMap) String() string {
+	return formatMap(m.All())
+}
+
+// Transient returns a transient copy of m, to assoc many keys onto
+// without copying any node more than once.
+func (m *PersistentHashMap) Transient() *TransientHashMap {
+	return &TransientHashMap{count: m.count, root: m.root, edit: &owner{}}
+}
+
+// TransientHashMap builds a map in place, it is not safe to use
+// from several goroutines nor after Persistent is called.
+type TransientHashMap struct {
+	count int
+	root  *hnode
+	edit  *owner
+}
+
+func (t *TransientHashMap) ensureEditable() {
+	if t.edit == nil {
+		panic("transient used after persistent!")
+	}
+}
+
+func (t *TransientHashMap) Count() int {
+	t.ensureEditable()
+	return t.count
+}
+
+// Find returns the value of key and whether t has it.
+func (t *TransientHashMap) Find(key Any) (Any, bool) {
+	t.ensureEditable()
+	return t.root.find(0, Hash(key), key)
+}
+
+// Assoc sets key to value.
+func (t *TransientHashMap) Assoc(key, value Any) *TransientHashMap {
+	t.ensureEditable()
+
+	added := false
+	t.root = t.root.assoc(t.edit, 0, hentry{key: key, value: value, hash: Hash(key)}, &added)
+	if added {
+		t.count++
+	}
+
+	return t
+}
+
+// Dissoc removes key.
+func (t *TransientHashMap) Dissoc(key Any) *TransientHashMap {
+	t.ensureEditable()
+
+	removed := false
+	t.root = t.root.dissoc(t.edit, 0, Hash(key), key, &removed)
+	if removed {
+		t.count--
+	}
+
+	return t
+}
+
+// Persistent returns the map built so far, t can't be used any
+// further.
+func (t *TransientHashMap) Persistent() *PersistentHashMap {
+	t.ensureEditable()
+	t.edit = nil
+
+	return &PersistentHashMap{count: t.count, root: t.root}
+}
+
+// formatMap prints maps the way they are written, {:a 1, :b 2}
+func formatMap(entries iter.Seq2[Any, Any]) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	first := true
+	for k, v := range entries {
+		if !first {
+			b.WriteString(", ")
+		}
+		first = false
+		fmt.Fprint(&b, k, " ", v)
+	}
+	b.WriteByte('}')
+
+	return b.String()
+}
@@ -0,0 +1,9 @@
+package core
+
+// Keyword is the value of a :keyword, used as map key. Being a
+// string, keywords with the same name are always equal.
+type Keyword string
+
+func (k Keyword) String() string {
+	return ":" + string(k)
+}
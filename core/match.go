@@ -0,0 +1,37 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// MatchError is raised when no clause of a match fits the value,
+// for a match over the variants of a union that is a nil value.
+type MatchError struct {
+	Value Any
+}
+
+func (e MatchError) Error() string {
+	return fmt.Sprintf("no match for: %#v", e.Value)
+}
+
+// IsVectorOf reports whether x is a vector (or any slice or array)
+// of n elements, or at least n if there is a rest pattern.
+func IsVectorOf(x Any, n int, rest bool) bool {
+	var length int
+
+	switch c := x.(type) {
+	case []Any:
+		length = len(c)
+	case indexed:
+		length = c.Count()
+	default:
+		v := reflect.ValueOf(x)
+		if k := v.Kind(); k != reflect.Slice && k != reflect.Array {
+			return false
+		}
+		length = v.Len()
+	}
+
+	return length == n || (rest && length > n)
+}
@@ -0,0 +1,33 @@
+package core
+
+import "testing"
+
+func TestIsVectorOf(t *testing.T) {
+	tests := []struct {
+		x    Any
+		n    int
+		rest bool
+		want bool
+	}{
+		{[]Any{1, 2}, 2, false, true},
+		{[]Any{1, 2}, 1, false, false},
+		{[]Any{1, 2}, 1, true, true},
+		{[]Any{}, 0, false, true},
+		{[]int{1}, 1, false, true},
+		{[2]string{}, 2, false, true},
+		{"ab", 2, false, false},
+		{nil, 0, true, false},
+	}
+
+	for _, test := range tests {
+		if got := IsVectorOf(test.x, test.n, test.rest); got != test.want {
+			t.Errorf("IsVectorOf(%v, %d, %v) = %v, want %v", test.x, test.n, test.rest, got, test.want)
+		}
+	}
+}
+
+func TestMatchError(t *testing.T) {
+	if got := (MatchError{Value: 1}).Error(); got != "no match for: 1" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"fmt"
+	"sync"
+)
+
+// MultiFn is a function calling the method added for the value its
+// dispatch function returns for the arguments, or the :default one.
+// Methods can be added at any time, from any package.
+type MultiFn struct {
+	name     string
+	dispatch Any
+
+	mu      sync.RWMutex
+	methods map[Any]Any
+}
+
+// NoMethodError is raised when a multimethod has neither a method
+// for the dispatch value nor a :default one.
+type NoMethodError struct {
+	Name  string
+	Value Any
+}
+
+func (e NoMethodError) Error() string {
+	return fmt.Sprintf("no method in multimethod %s for dispatch value: %v", e.Name, e.Value)
+}
+
+// NewMultiFn makes a multimethod dispatching on the result of
+// calling dispatch, which may also be a keyword to look up in the
+// first argument.
+func NewMultiFn(name string, dispatch Any) *MultiFn {
+	return &MultiFn{name: name, dispatch: dispatch, methods: map[Any]Any{}}
+}
+
+// AddMethod adds (or replaces) the method for the dispatch value.
+func (m *MultiFn) AddMethod(value Any, method Any) *MultiFn {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.methods[value] = method
+	return m
+}
+
+func (m *MultiFn) Call(args ...Any) Any {
+	var value Any
+	if k, ok := m.dispatch.(Keyword); ok {
+		if len(args) == 0 {
+			panic(ArityError(0))
+		}
+		value = GetKey(args[0], k)
+	} else {
+		value = call(m.dispatch, args...)
+	}
+
+	m.mu.RLock()
+	method, ok := m.methods[value]
+	if !ok {
+		method, ok = m.methods[Keyword("default")]
+	}
+	m.mu.RUnlock()
+
+	if !ok {
+		panic(NoMethodError{Name: m.name, Value: value})
+	}
+
+	return call(method, args...)
+}
@@ -0,0 +1,45 @@
+package core
+
+import "testing"
+
+func TestMultiFn(t *testing.T) {
+	speak := NewMultiFn("speak", Keyword("kind"))
+	speak.AddMethod(Keyword("dog"), func(a Any) Any { return "woof" })
+
+	dog := map[Any]Any{Keyword("kind"): Keyword("dog")}
+	if got := speak.Call(dog); got != "woof" {
+		t.Errorf("speak(dog) = %v", got)
+	}
+
+	cat := map[Any]Any{Keyword("kind"): Keyword("cat")}
+	func() {
+		defer func() {
+			err, ok := recover().(NoMethodError)
+			if !ok || err.Value != Keyword("cat") {
+				t.Errorf("speak(cat) panicked with %v, want a NoMethodError", err)
+			}
+		}()
+		speak.Call(cat)
+	}()
+
+	speak.AddMethod(Keyword("default"), func(a Any) Any { return "..." })
+	if got := speak.Call(cat); got != "..." {
+		t.Errorf("speak(cat) = %v, want the default", got)
+	}
+}
+
+func TestMultiFnDispatchFn(t *testing.T) {
+	add := NewMultiFn("add", func(a, b Any) Any { return a.(int) + b.(int) })
+	add.AddMethod(2, func(a, b Any) Any { return "two" })
+
+	if got := add.Call(1, 1); got != "two" {
+		t.Errorf("add(1, 1) = %v", got)
+	}
+}
+
+func TestNoImplError(t *testing.T) {
+	err := NoImplError{Protocol: "Shape", Value: 1}
+	if got := err.Error(); got != "no implementation of protocol Shape for int" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,506 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"sync/atomic"
+)
+
+// ArithmeticError is raised by integer (and ratio) division by zero.
+type ArithmeticError string
+
+func (e ArithmeticError) Error() string {
+	return string(e)
+}
+
+// The numeric tower: arithmetic promotes its arguments to the widest
+// kind among them, so ints stay ints.
+type numKind int
+
+const (
+	intKind numKind = iota
+	bigIntKind
+	ratioKind
+	floatKind
+	complexKind
+)
+
+// toNumber normalizes x to an int, *big.Int, *big.Rat, float64 or
+// complex128, i.e. an int8 becomes an int and a float32 a float64.
+func toNumber(x Any) (Any, numKind) {
+	switch n := x.(type) {
+	case int:
+		return n, intKind
+	case float64:
+		return n, floatKind
+	case *big.Int:
+		return n, bigIntKind
+	case *big.Rat:
+		return n, ratioKind
+	case complex128:
+		return n, complexKind
+	}
+
+	v := reflect.ValueOf(x)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(v.Int()), intKind
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt {
+			return new(big.Int).SetUint64(v.Uint()), bigIntKind
+		}
+		return int(v.Uint()), intKind
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), floatKind
+	case reflect.Complex64, reflect.Complex128:
+		return v.Complex(), complexKind
+	}
+
+	panic(fmt.Sprintf("not a number: %#v", x))
+}
+
+// promote converts the normalized number n of kind from to the
+// wider kind to.
+func promote(n Any, from, to numKind) Any {
+	if from == to {
+		return n
+	}
+
+	switch to {
+	case bigIntKind:
+		return big.NewInt(int64(n.(int)))
+
+	case ratioKind:
+		if from == intKind {
+			return new(big.Rat).SetInt64(int64(n.(int)))
+		}
+		return new(big.Rat).SetInt(n.(*big.Int))
+
+	case floatKind:
+		switch n := n.(type) {
+		case int:
+			return float64(n)
+		case *big.Int:
+			f, _ := new(big.Float).SetInt(n).Float64()
+			return f
+		case *big.Rat:
+			f, _ := n.Float64()
+			return f
+		}
+
+	case complexKind:
+		return complex(promote(n, from, floatKind).(float64), 0)
+	}
+
+	panic(fmt.Sprintf("can't promote %#v", n))
+}
+
+var autoPromote atomic.Bool
+
+// SetAutoPromote makes int arithmetic overflowing promote its result
+// to a *big.Int, instead of raising an ArithmeticError.
+func SetAutoPromote(on bool) {
+	autoPromote.Store(on)
+}
+
+// overflowed returns the big.Int result of an int operation that
+// overflowed, if auto promotion is on.
+func overflowed(result func() *big.Int) *big.Int {
+	if !autoPromote.Load() {
+		panic(ArithmeticError("integer overflow"))
+	}
+
+	return result()
+}
+
+// bothInts is the fast path of the arithmetic, most numbers being
+// plain ints.
+func bothInts(a, b Any) (int, int, bool) {
+	x, ok := a.(int)
+	if !ok {
+		return 0, 0, false
+	}
+
+	y, ok := b.(int)
+	return x, y, ok
+}
+
+// align normalizes both numbers and promotes them to a common kind.
+func align(a, b Any) (Any, Any, numKind) {
+	x, kx := toNumber(a)
+	y, ky := toNumber(b)
+	kind := max(kx, ky)
+
+	return promote(x, kx, kind), promote(y, ky, kind), kind
+}
+
+// normalizeRatio turns whole ratios back into integers.
+func normalizeRatio(r *big.Rat) Any {
+	if !r.IsInt() {
+		return r
+	}
+
+	if n := r.Num(); n.IsInt64() {
+		return int(n.Int64())
+	}
+
+	return new(big.Int).Set(r.Num())
+}
+
+var (
+	bigIntType = reflect.TypeOf((*big.Int)(nil))
+	ratioType  = reflect.TypeOf((*big.Rat)(nil))
+)
+
+// convertBig converts numbers from and to *big.Int and *big.Rat, for
+// As. Big integers only convert to ints if they fit.
+func convertBig(v Any, typ reflect.Type) (Any, bool) {
+	if !isNumber(v) {
+		return nil, false
+	}
+	n, kind := toNumber(v)
+
+	switch {
+	case typ == bigIntType && kind <= ratioKind:
+		if r, ok := promote(n, kind, ratioKind).(*big.Rat); ok && r.IsInt() {
+			return new(big.Int).Set(r.Num()), true
+		}
+
+	case typ == ratioType && kind <= ratioKind:
+		return new(big.Rat).Set(promote(n, kind, ratioKind).(*big.Rat)), true
+
+	case typ == ratioType && kind == floatKind:
+		if r := new(big.Rat).SetFloat64(n.(float64)); r != nil {
+			return r, true
+		}
+
+	case kind == bigIntKind && reflect.Int <= typ.Kind() && typ.Kind() <= reflect.Uintptr:
+		if i := n.(*big.Int); i.IsInt64() {
+			return reflect.ValueOf(i.Int64()).Convert(typ).Interface(), true
+		}
+		panic(fmt.Sprintf("%v doesn't fit into %v", n, typ))
+
+	case (kind == bigIntKind || kind == ratioKind) && isNumberKind(typ.Kind()):
+		return reflect.ValueOf(promote(n, kind, floatKind)).Convert(typ).Interface(), true
+	}
+
+	return nil, false
+}
+
+// A numOp is a binary arithmetic operation over every kind of number,
+// the int version reporting whether the result didn't overflow.
+type numOp struct {
+	int     func(a, b int) (int, bool)
+	bigInt  func(z, a, b *big.Int) *big.Int
+	ratio   func(z, a, b *big.Rat) *big.Rat
+	float   func(a, b float64) float64
+	complex func(a, b complex128) complex128
+}
+
+func (op numOp) apply(a, b Any) Any {
+	if x, y, ok := bothInts(a, b); ok {
+		if n, ok := op.int(x, y); ok {
+			return n
+		}
+	}
+
+	a, b, kind := align(a, b)
+
+	switch kind {
+	case intKind:
+		if n, ok := op.int(a.(int), b.(int)); ok {
+			return n
+		}
+		return overflowed(func() *big.Int {
+			return op.bigInt(new(big.Int), big.NewInt(int64(a.(int))), big.NewInt(int64(b.(int))))
+		})
+	case bigIntKind:
+		return op.bigInt(new(big.Int), a.(*big.Int), b.(*big.Int))
+	case ratioKind:
+		return normalizeRatio(op.ratio(new(big.Rat), a.(*big.Rat), b.(*big.Rat)))
+	case floatKind:
+		return op.float(a.(float64), b.(float64))
+	default:
+		return op.complex(a.(complex128), b.(complex128))
+	}
+}
+
+var (
+	addOp = numOp{
+		int: func(a, b int) (int, bool) {
+			c := a + b
+			return c, (c > a) == (b > 0) || b == 0
+		},
+		bigInt:  (*big.Int).Add,
+		ratio:   (*big.Rat).Add,
+		float:   func(a, b float64) float64 { return a + b },
+		complex: func(a, b complex128) complex128 { return a + b },
+	}
+
+	subOp = numOp{
+		int: func(a, b int) (int, bool) {
+			c := a - b
+			return c, (c < a) == (b > 0) || b == 0
+		},
+		bigInt:  (*big.Int).Sub,
+		ratio:   (*big.Rat).Sub,
+		float:   func(a, b float64) float64 { return a - b },
+		complex: func(a, b complex128) complex128 { return a - b },
+	}
+
+	mulOp = numOp{
+		int: func(a, b int) (int, bool) {
+			if a == 0 || b == 0 {
+				return 0, true
+			}
+			c := a * b
+			return c, c/b == a && !(a == -1 && b == math.MinInt) && !(b == -1 && a == math.MinInt)
+		},
+		bigInt:  (*big.Int).Mul,
+		ratio:   (*big.Rat).Mul,
+		float:   func(a, b float64) float64 { return a * b },
+		complex: func(a, b complex128) complex128 { return a * b },
+	}
+)
+
+func ADD(args ...Any) Any {
+	if len(args) == 0 {
+		return 0
+	}
+
+	sum, _ := toNumber(args[0])
+	for _, n := range args[1:] {
+		sum = addOp.apply(sum, n)
+	}
+
+	return sum
+}
+
+// SUB subtracts the rest of the arguments from the first one, or
+// negates a single argument.
+func SUB(args ...Any) Any {
+	switch len(args) {
+	case 0:
+		panic(ArityError(0))
+	case 1:
+		return subOp.apply(0, args[0])
+	}
+
+	result := args[0]
+	for _, n := range args[1:] {
+		result = subOp.apply(result, n)
+	}
+
+	return result
+}
+
+func MUL(args ...Any) Any {
+	if len(args) == 0 {
+		return 1
+	}
+
+	prod, _ := toNumber(args[0])
+	for _, n := range args[1:] {
+		prod = mulOp.apply(prod, n)
+	}
+
+	return prod
+}
+
+// DIV divides the first argument by the rest, or gives the inverse
+// of a single argument. Dividing integers is exact, giving a ratio
+// unless the result is whole:
+//
+//	(/ 6 3)  2
+//	(/ 1 3)  1/3
+//	(/ 1.0 3)  0.3333333333333333
+func DIV(args ...Any) Any {
+	switch len(args) {
+	case 0:
+		panic(ArityError(0))
+	case 1:
+		return divide(1, args[0])
+	}
+
+	result := args[0]
+	for _, n := range args[1:] {
+		result = divide(result, n)
+	}
+
+	return result
+}
+
+func divide(a, b Any) Any {
+	a, b, kind := align(a, b)
+
+	switch kind {
+	case floatKind:
+		return a.(float64) / b.(float64)
+	case complexKind:
+		return a.(complex128) / b.(complex128)
+	}
+
+	x := promote(a, kind, ratioKind).(*big.Rat)
+	y := promote(b, kind, ratioKind).(*big.Rat)
+	if y.Sign() == 0 {
+		panic(ArithmeticError("divide by zero"))
+	}
+
+	return normalizeRatio(new(big.Rat).Quo(x, y))
+}
+
+// Quot is the quotient of dividing a by b, truncated towards zero.
+func Quot(a, b Any) Any {
+	if x, y, ok := bothInts(a, b); ok && y != 0 && y != -1 {
+		return x / y
+	}
+
+	a, b, kind := align(a, b)
+	checkDivisor(b)
+
+	switch kind {
+	case intKind:
+		if a.(int) == math.MinInt && b.(int) == -1 {
+			return overflowed(func() *big.Int {
+				return new(big.Int).Neg(big.NewInt(math.MinInt))
+			})
+		}
+		return a.(int) / b.(int)
+	case bigIntKind:
+		return new(big.Int).Quo(a.(*big.Int), b.(*big.Int))
+	case ratioKind:
+		q := new(big.Rat).Quo(a.(*big.Rat), b.(*big.Rat))
+		return normalizeRatio(new(big.Rat).SetInt(new(big.Int).Quo(q.Num(), q.Denom())))
+	case floatKind:
+		return math.Trunc(a.(float64) / b.(float64))
+	}
+
+	panic("quot needs real numbers!")
+}
+
+// Rem is the remainder of dividing a by b, having the sign of a.
+func Rem(a, b Any) Any {
+	if x, y, ok := bothInts(a, b); ok && y != 0 {
+		return x % y
+	}
+
+	a, b, kind := align(a, b)
+	checkDivisor(b)
+
+	switch kind {
+	case intKind:
+		return a.(int) % b.(int)
+	case bigIntKind:
+		return new(big.Int).Rem(a.(*big.Int), b.(*big.Int))
+	case ratioKind:
+		return SUB(a, MUL(b, Quot(a, b)))
+	case floatKind:
+		return math.Mod(a.(float64), b.(float64))
+	}
+
+	panic("rem needs real numbers!")
+}
+
+// MOD is the modulus of dividing a by b, having the sign of b.
+func MOD(a, b Any) Any {
+	if x, y, ok := bothInts(a, b); ok && y != 0 {
+		m := x % y
+		if m != 0 && (m < 0) != (y < 0) {
+			m += y
+		}
+		return m
+	}
+
+	m := Rem(a, b)
+	if sign(m) != 0 && sign(m) != sign(b) {
+		return ADD(m, b)
+	}
+
+	return m
+}
+
+func checkDivisor(n Any) {
+	if _, isFloat := n.(float64); !isFloat && sign(n) == 0 {
+		panic(ArithmeticError("divide by zero"))
+	}
+}
+
+func sign(n Any) int {
+	switch n := n.(type) {
+	case *big.Int:
+		return n.Sign()
+	case *big.Rat:
+		return n.Sign()
+	case complex128:
+		panic("complex numbers have no sign!")
+	}
+
+	return compareNumbers(n, 0)
+}
+
+// compareNumbers returns -1, 0 or 1 if a is less than, equal to or
+// greater than b. Complex numbers can't be ordered.
+func compareNumbers(a, b Any) int {
+	if x, y, ok := bothInts(a, b); ok {
+		return cmpOrdered(x, y)
+	}
+
+	a, b, kind := align(a, b)
+
+	switch kind {
+	case intKind:
+		return cmpOrdered(a.(int), b.(int))
+	case bigIntKind:
+		return a.(*big.Int).Cmp(b.(*big.Int))
+	case ratioKind:
+		return a.(*big.Rat).Cmp(b.(*big.Rat))
+	case floatKind:
+		return compareFloats(a.(float64), b.(float64))
+	}
+
+	panic("complex numbers can't be compared!")
+}
+
+// compareFloats orders NaN after every other number and as equal to
+// itself (as Java's Double.compare does), rather than unordered, so
+// that sorting and sorted collections stay consistent with one.
+func compareFloats(a, b float64) int {
+	switch nanA, nanB := math.IsNaN(a), math.IsNaN(b); {
+	case nanA && nanB:
+		return 0
+	case nanA:
+		return 1
+	case nanB:
+		return -1
+	}
+
+	return cmpOrdered(a, b)
+}
+
+func cmpOrdered[T int | float64 | string](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+
+	return 0
+}
+
+// equalNumbers reports whether both numbers have the same value,
+// whatever their kinds.
+func equalNumbers(a, b Any) bool {
+	if x, y, ok := bothInts(a, b); ok {
+		return x == y
+	}
+
+	a, b, kind := align(a, b)
+	if kind == complexKind {
+		return a == b
+	}
+
+	return compareNumbers(a, b) == 0
+}
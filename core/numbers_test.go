@@ -0,0 +1,110 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"testing"
+)
+
+// recovered returns what f panics with, nil if it doesn't panic.
+func recovered(f func()) (r any) {
+	defer func() { r = recover() }()
+	f()
+	return nil
+}
+
+func TestArithmetic(t *testing.T) {
+	third := big.NewRat(1, 3)
+
+	tests := []struct {
+		name      string
+		got, want Any
+	}{
+		{"(+)", ADD(), 0},
+		{"(+ 1 2)", ADD(1, 2), 3},
+		{"(+ 1 2.5)", ADD(1, 2.5), 3.5},
+		{"(+ int8 int)", ADD(int8(1), 2), 3},
+		{"(+ 1N 1)", ADD(big.NewInt(1), 1), big.NewInt(2)},
+		{"(+ 1/3 1/3 1/3)", ADD(third, third, third), 1},
+		{"(+ 1/4 0.5)", ADD(big.NewRat(1, 4), 0.5), 0.75},
+		{"(- 5)", SUB(5), -5},
+		{"(- 5 1 1)", SUB(5, 1, 1), 3},
+		{"(* 2 3)", MUL(2, 3), 6},
+		{"(* 1/3 3)", MUL(third, 3), 1},
+		{"(* 2 1.5)", MUL(2, 1.5), 3.0},
+		{"(/ 6 3)", DIV(6, 3), 2},
+		{"(/ 1 3)", DIV(1, 3), third},
+		{"(/ 3)", DIV(3), third},
+		{"(/ 1.0 4)", DIV(1.0, 4), 0.25},
+		{"(/ 1N 2)", DIV(big.NewInt(1), 2), big.NewRat(1, 2)},
+		{"(/ 1.0 0)", DIV(1.0, 0), math.Inf(1)},
+		{"(quot 7 -2)", Quot(7, -2), -3},
+		{"(quot 7.5 2)", Quot(7.5, 2), 3.0},
+		{"(quot 7/2 1)", Quot(big.NewRat(7, 2), 1), 3},
+		{"(rem -7 2)", Rem(-7, 2), -1},
+		{"(rem 7.5 2)", Rem(7.5, 2), 1.5},
+		{"(mod -7 2)", MOD(-7, 2), 1},
+		{"(mod 7 -2)", MOD(7, -2), -1},
+		{"(mod -7N 2)", MOD(big.NewInt(-7), 2), big.NewInt(1)},
+		{"(+ MaxInt 0)", ADD(math.MaxInt, 0), math.MaxInt},
+		{"(- MinInt -1)", SUB(math.MinInt, -1), math.MinInt + 1},
+	}
+
+	for _, test := range tests {
+		// the kind of number matters as well as its value
+		if fmt.Sprintf("%T", test.got) != fmt.Sprintf("%T", test.want) || !Equal(test.got, test.want) {
+			t.Errorf("%s: got %T %v, want %T %v", test.name, test.got, test.got, test.want, test.want)
+		}
+	}
+}
+
+func TestOverflow(t *testing.T) {
+	overflows := []struct {
+		name string
+		f    func() Any
+		want *big.Int
+	}{
+		{"(+ MaxInt 1)", func() Any { return ADD(math.MaxInt, 1) }, new(big.Int).Add(big.NewInt(math.MaxInt), big.NewInt(1))},
+		{"(- MinInt 1)", func() Any { return SUB(math.MinInt, 1) }, new(big.Int).Sub(big.NewInt(math.MinInt), big.NewInt(1))},
+		{"(- MinInt)", func() Any { return SUB(math.MinInt) }, new(big.Int).Neg(big.NewInt(math.MinInt))},
+		{"(* MaxInt 2)", func() Any { return MUL(math.MaxInt, 2) }, new(big.Int).Mul(big.NewInt(math.MaxInt), big.NewInt(2))},
+		{"(* MinInt -1)", func() Any { return MUL(math.MinInt, -1) }, new(big.Int).Neg(big.NewInt(math.MinInt))},
+		{"(quot MinInt -1)", func() Any { return Quot(math.MinInt, -1) }, new(big.Int).Neg(big.NewInt(math.MinInt))},
+	}
+
+	for _, test := range overflows {
+		r := recovered(func() { test.f() })
+		if _, ok := r.(ArithmeticError); !ok {
+			t.Errorf("%s: got panic %v, want an ArithmeticError", test.name, r)
+		}
+	}
+
+	SetAutoPromote(true)
+	defer SetAutoPromote(false)
+
+	for _, test := range overflows {
+		if got := test.f(); !Equal(got, test.want) {
+			t.Errorf("%s promoted: got %T %v, want %v", test.name, got, got, test.want)
+		}
+	}
+}
+
+func TestDivideByZero(t *testing.T) {
+	tests := []struct {
+		name string
+		f    func()
+	}{
+		{"(/ 1 0)", func() { DIV(1, 0) }},
+		{"(/ 1/3 0)", func() { DIV(big.NewRat(1, 3), 0) }},
+		{"(quot 1 0)", func() { Quot(1, 0) }},
+		{"(rem 1N 0)", func() { Rem(big.NewInt(1), 0) }},
+		{"(mod 1 0)", func() { MOD(1, 0) }},
+	}
+
+	for _, test := range tests {
+		if _, ok := recovered(test.f).(ArithmeticError); !ok {
+			t.Errorf("%s didn't raise an ArithmeticError", test.name)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package core
+
+import "fmt"
+
+// NoImplError is raised when a protocol function is called on a
+// value whose type doesn't implement the protocol.
+type NoImplError struct {
+	Protocol string
+	Value    Any
+}
+
+func (e NoImplError) Error() string {
+	return fmt.Sprintf("no implementation of protocol %s for %T", e.Protocol, e.Value)
+}
@@ -0,0 +1,94 @@
+package core
+
+import (
+	"fmt"
+	"iter"
+	"reflect"
+)
+
+// Range returns an iterator over the index/key and value pairs of
+// a vector, string, map, set or channel, so that doseq can range
+// over values only known to be of type Any.
+func Range(coll Any) iter.Seq2[Any, Any] {
+	return func(yield func(Any, Any) bool) {
+		switch c := coll.(type) {
+		case nil:
+			return
+		case []Any:
+			for i, v := range c {
+				if !yield(i, v) {
+					return
+				}
+			}
+		case string:
+			for i, r := range c {
+				if !yield(i, r) {
+					return
+				}
+			}
+		case *PersistentVector:
+			for i, v := range c.All() {
+				if !yield(i, v) {
+					return
+				}
+			}
+		case map[Any]Any:
+			for k, v := range c {
+				if !yield(k, v) {
+					return
+				}
+			}
+		case associative:
+			for k, v := range c.All() {
+				if !yield(k, v) {
+					return
+				}
+			}
+		case setLike:
+			for x := range c.All() {
+				if !yield(x, x) {
+					return
+				}
+			}
+		case chan Any:
+			i := 0
+			for v := range c {
+				if !yield(i, v) {
+					return
+				}
+				i++
+			}
+		default:
+			rangeReflect(coll, yield)
+		}
+	}
+}
+
+func rangeReflect(coll Any, yield func(Any, Any) bool) {
+	v := reflect.ValueOf(coll)
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if !yield(i, v.Index(i).Interface()) {
+				return
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if !yield(iter.Key().Interface(), iter.Value().Interface()) {
+				return
+			}
+		}
+	case reflect.Chan:
+		for i := 0; ; i++ {
+			elem, ok := v.Recv()
+			if !ok || !yield(i, elem.Interface()) {
+				return
+			}
+		}
+	default:
+		panic(fmt.Sprintf("can't range over %T", coll))
+	}
+}
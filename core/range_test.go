@@ -0,0 +1,52 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+var rangeTests = []struct {
+	coll Any
+	keys []Any
+	vals []Any
+}{
+	{nil, nil, nil},
+	{[]Any{"a", "b"}, []Any{0, 1}, []Any{"a", "b"}},
+	{"hé", []Any{0, 1}, []Any{'h', 'é'}},
+	{map[Any]Any{1: "one"}, []Any{1}, []Any{"one"}},
+	{[]int{3, 4}, []Any{0, 1}, []Any{3, 4}},
+}
+
+func TestRange(t *testing.T) {
+	for _, test := range rangeTests {
+		var keys, vals []Any
+		for k, v := range Range(test.coll) {
+			keys = append(keys, k)
+			vals = append(vals, v)
+		}
+
+		if !reflect.DeepEqual(keys, test.keys) || !reflect.DeepEqual(vals, test.vals) {
+			t.Errorf("Range(%v) = %v, %v, want %v, %v", test.coll, keys, vals, test.keys, test.vals)
+		}
+	}
+}
+
+func TestRangeStops(t *testing.T) {
+	ch := make(chan Any, 3)
+	ch <- 1
+	ch <- 2
+	ch <- 3
+	close(ch)
+
+	var vals []Any
+	for _, v := range Range(ch) {
+		if v == 2 {
+			break
+		}
+		vals = append(vals, v)
+	}
+
+	if !reflect.DeepEqual(vals, []Any{1}) {
+		t.Errorf("got %v, want [1]", vals)
+	}
+}
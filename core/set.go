@@ -0,0 +1,196 @@
+package core
+
+import (
+	"fmt"
+	"iter"
+	"strings"
+)
+
+// Set is a mutable set, a Go map of its elements.
+type Set map[Any]struct{}
+
+// Contains reports whether x is an element of the set.
+func (s Set) Contains(x Any) bool {
+	_, ok := s[x]
+	return ok
+}
+
+func (s Set) Count() int {
+	return len(s)
+}
+
+// All iterates over the elements of s, in no particular order.
+func (s Set) All() iter.Seq[Any] {
+	return func(yield func(Any) bool) {
+		for x := range s {
+			if !yield(x) {
+				return
+			}
+		}
+	}
+}
+
+// PersistentHashSet is what #{...} literals evaluate to, an immutable
+// set of Equal values, the keys of a PersistentHashMap.
+type PersistentHashSet struct {
+	m *PersistentHashMap
+}
+
+// HashSet returns a persistent set of xs.
+func HashSet(xs ...Any) *PersistentHashSet {
+	t := (&PersistentHashSet{emptyHashMap}).Transient()
+	for _, x := range xs {
+		t.Conj(x)
+	}
+
+	return t.Persistent()
+}
+
+func (s *PersistentHashSet) Count() int {
+	return s.m.Count()
+}
+
+// Contains reports whether x is an element of the set.
+func (s *PersistentHashSet) Contains(x Any) bool {
+	_, ok := s.m.Find(x)
+	return ok
+}
+
+// Conj returns a set with x added.
+func (s *PersistentHashSet) Conj(x Any) *PersistentHashSet {
+	if s.Contains(x) {
+		return s
+	}
+
+	return &PersistentHashSet{s.m.Assoc(x, x)}
+}
+
+// Disj returns a set without x.
+func (s *PersistentHashSet) Disj(x Any) *PersistentHashSet {
+	return &PersistentHashSet{s.m.Dissoc(x)}
+}
+
+// All iterates over the elements of s, in no particular order.
+func (s *PersistentHashSet) All() iter.Seq[Any] {
+	return keys(s.m.All())
+}
+
+func (s *PersistentHashSet) String() string {
+	return formatSet(s.All())
+}
+
+// Transient returns a transient copy of s, to conj many elements
+// onto without copying any node more than once.
+func (s *PersistentHashSet) Transient() *TransientHashSet {
+	return &TransientHashSet{s.m.Transient()}
+}
+
+// TransientHashSet builds a set in place, it is not safe to use
+// from several goroutines nor after Persistent is called.
+type TransientHashSet struct {
+	m *TransientHashMap
+}
+
+func (t *TransientHashSet) Count() int {
+	return t.m.Count()
+}
+
+// Contains reports whether x is an element of the set.
+func (t *TransientHashSet) Contains(x Any) bool {
+	_, ok := t.m.Find(x)
+	return ok
+}
+
+// Conj adds x to the set.
+func (t *TransientHashSet) Conj(x Any) *TransientHashSet {
+	t.m.Assoc(x, x)
+	return t
+}
+
+// Disj removes x from the set.
+func (t *TransientHashSet) Disj(x Any) *TransientHashSet {
+	t.m.Dissoc(x)
+	return t
+}
+
+// Persistent returns the set built so far, t can't be used any
+// further.
+func (t *TransientHashSet) Persistent() *PersistentHashSet {
+	return &PersistentHashSet{t.m.Persistent()}
+}
+
+// PersistentSortedSet is an immutable set keeping its elements in
+// the order of Compare, the keys of a PersistentSortedMap.
+type PersistentSortedSet struct {
+	m *PersistentSortedMap
+}
+
+// SortedSet returns a persistent sorted set of xs.
+func SortedSet(xs ...Any) *PersistentSortedSet {
+	s := &PersistentSortedSet{&PersistentSortedMap{}}
+	for _, x := range xs {
+		s = s.Conj(x)
+	}
+
+	return s
+}
+
+func (s *PersistentSortedSet) Count() int {
+	return s.m.Count()
+}
+
+// Contains reports whether x is an element of the set.
+func (s *PersistentSortedSet) Contains(x Any) bool {
+	_, ok := s.m.Find(x)
+	return ok
+}
+
+// Conj returns a set with x added.
+func (s *PersistentSortedSet) Conj(x Any) *PersistentSortedSet {
+	if s.Contains(x) {
+		return s
+	}
+
+	return &PersistentSortedSet{s.m.Assoc(x, x)}
+}
+
+// Disj returns a set without x.
+func (s *PersistentSortedSet) Disj(x Any) *PersistentSortedSet {
+	return &PersistentSortedSet{s.m.Dissoc(x)}
+}
+
+// All iterates over the elements of s, smallest first.
+func (s *PersistentSortedSet) All() iter.Seq[Any] {
+	return keys(s.m.All())
+}
+
+func (s *PersistentSortedSet) String() string {
+	return formatSet(s.All())
+}
+
+func keys(entries iter.Seq2[Any, Any]) iter.Seq[Any] {
+	return func(yield func(Any) bool) {
+		for k := range entries {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
+
+// formatSet prints sets the way they are written, #{1 2 3}
+func formatSet(elems iter.Seq[Any]) string {
+	var b strings.Builder
+	b.WriteString("#{")
+	first := true
+	for x := range elems {
+		if !first {
+			b.WriteByte(' ')
+		}
+		first = false
+		fmt.Fprint(&b, x)
+	}
+	b.WriteByte('}')
+
+	return b.String()
+}
@@ -0,0 +1,35 @@
+package core
+
+import "testing"
+
+func TestSet(t *testing.T) {
+	s := Set{1: {}, Keyword("a"): {}}
+
+	for _, x := range []Any{1, Keyword("a")} {
+		if !s.Contains(x) {
+			t.Errorf("%v isn't in %v", x, s)
+		}
+		if got := Get(x, s); got != x {
+			t.Errorf("Get(%v, s) = %v, want %v", x, got, x)
+		}
+	}
+
+	if s.Contains("a") {
+		t.Errorf("the string \"a\" is in %v", s)
+	}
+
+	if got := Get(2, "default", s); got != "default" {
+		t.Errorf("Get(2, default, s) = %v", got)
+	}
+
+	n := 0
+	for k, v := range Range(s) {
+		if k != v || !s.Contains(k) {
+			t.Errorf("Range yielded %v, %v", k, v)
+		}
+		n++
+	}
+	if n != 2 {
+		t.Errorf("Range yielded %d elements, want 2", n)
+	}
+}
@@ -0,0 +1,200 @@
+package core
+
+import "iter"
+
+// tnode is a node of an AVL tree, never changed once created
+type tnode struct {
+	key, value  Any
+	left, right *tnode
+	height      int
+}
+
+func (n *tnode) depth() int {
+	if n == nil {
+		return 0
+	}
+
+	return n.height
+}
+
+func newTNode(key, value Any, left, right *tnode) *tnode {
+	return &tnode{key: key, value: value, left: left, right: right, height: 1 + max(left.depth(), right.depth())}
+}
+
+func rotateLeft(n *tnode) *tnode {
+	r := n.right
+	return newTNode(r.key, r.value, newTNode(n.key, n.value, n.left, r.left), r.right)
+}
+
+func rotateRight(n *tnode) *tnode {
+	l := n.left
+	return newTNode(l.key, l.value, l.left, newTNode(n.key, n.value, l.right, n.right))
+}
+
+// balance returns a node of key and value over left and right, whose
+// depths differ by at most 2, rotated back into an AVL tree.
+func balance(key, value Any, left, right *tnode) *tnode {
+	n := newTNode(key, value, left, right)
+
+	switch diff := left.depth() - right.depth(); {
+	case diff > 1:
+		if left.left.depth() < left.right.depth() {
+			n = newTNode(key, value, rotateLeft(left), right)
+		}
+		return rotateRight(n)
+	case diff < -1:
+		if right.right.depth() < right.left.depth() {
+			n = newTNode(key, value, left, rotateRight(right))
+		}
+		return rotateLeft(n)
+	}
+
+	return n
+}
+
+func (n *tnode) find(key Any) (Any, bool) {
+	for n != nil {
+		switch c := Compare(key, n.key); {
+		case c < 0:
+			n = n.left
+		case c > 0:
+			n = n.right
+		default:
+			return n.value, true
+		}
+	}
+
+	return nil, false
+}
+
+func (n *tnode) assoc(key, value Any, added *bool) *tnode {
+	if n == nil {
+		*added = true
+		return newTNode(key, value, nil, nil)
+	}
+
+	switch c := Compare(key, n.key); {
+	case c < 0:
+		return balance(n.key, n.value, n.left.assoc(key, value, added), n.right)
+	case c > 0:
+		return balance(n.key, n.value, n.left, n.right.assoc(key, value, added))
+	}
+
+	return newTNode(n.key, value, n.left, n.right)
+}
+
+func (n *tnode) dissoc(key Any, removed *bool) *tnode {
+	if n == nil {
+		return nil
+	}
+
+	switch c := Compare(key, n.key); {
+	case c < 0:
+		left := n.left.dissoc(key, removed)
+		if left == n.left {
+			return n
+		}
+		return balance(n.key, n.value, left, n.right)
+	case c > 0:
+		right := n.right.dissoc(key, removed)
+		if right == n.right {
+			return n
+		}
+		return balance(n.key, n.value, n.left, right)
+	}
+
+	*removed = true
+	switch {
+	case n.left == nil:
+		return n.right
+	case n.right == nil:
+		return n.left
+	}
+
+	// the smallest key on the right takes the place of n
+	next := n.right
+	for next.left != nil {
+		next = next.left
+	}
+
+	return balance(next.key, next.value, n.left, n.right.dissocMin())
+}
+
+func (n *tnode) dissocMin() *tnode {
+	if n.left == nil {
+		return n.right
+	}
+
+	return balance(n.key, n.value, n.left.dissocMin(), n.right)
+}
+
+func (n *tnode) each(yield func(Any, Any) bool) bool {
+	return n == nil || (n.left.each(yield) && yield(n.key, n.value) && n.right.each(yield))
+}
+
+// PersistentSortedMap is an immutable map keeping its keys in the
+// order of Compare, a balanced tree sharing its structure with the
+// maps it was made from.
+type PersistentSortedMap struct {
+	count int
+	root  *tnode
+}
+
+// SortedMap returns a persistent sorted map of the keys and values
+// in kvs, later keys replacing earlier equal ones.
+func SortedMap(kvs ...Any) *PersistentSortedMap {
+	if len(kvs)%2 != 0 {
+		panic("sorted-map needs an even number of keys and values!")
+	}
+
+	m := &PersistentSortedMap{}
+	for i := 0; i < len(kvs); i += 2 {
+		m = m.Assoc(kvs[i], kvs[i+1])
+	}
+
+	return m
+}
+
+func (m *PersistentSortedMap) Count() int {
+	return m.count
+}
+
+// Find returns the value of key and whether m has it.
+func (m *PersistentSortedMap) Find(key Any) (Any, bool) {
+	return m.root.find(key)
+}
+
+// Assoc returns a map with key set to value.
+func (m *PersistentSortedMap) Assoc(key, value Any) *PersistentSortedMap {
+	added := false
+	root := m.root.assoc(key, value, &added)
+
+	count := m.count
+	if added {
+		count++
+	}
+
+	return &PersistentSortedMap{count: count, root: root}
+}
+
+// Dissoc returns a map without key.
+func (m *PersistentSortedMap) Dissoc(key Any) *PersistentSortedMap {
+	removed := false
+	root := m.root.dissoc(key, &removed)
+	if !removed {
+		return m
+	}
+
+	return &PersistentSortedMap{count: m.count - 1, root: root}
+}
+
+// All iterates over the keys and values of m, smallest key first.
+func (m *PersistentSortedMap) All() iter.Seq2[Any, Any] {
+	return func(yield func(Any, Any) bool) {
+		m.root.each(yield)
+	}
+}
+
+func (m *PersistentSortedMap) String() string {
+	return formatMap(m.All())
+}
@@ -0,0 +1,53 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Store assigns v to the variable dst points to, i.e. a local or an
+// element of a typed slice, converting it like As. Unlike As, it
+// panics rather than lose part of a number, storing 2.5 in an int.
+func Store[T any](dst *T, v Any) T {
+	t := As[T](v)
+	if isNumber(v) && !Equal(t, v) {
+		panic(fmt.Sprintf("can't store %v as %T without losing part of it", v, t))
+	}
+
+	*dst = t
+	return t
+}
+
+// As asserts v to be a T, the result of a typed fn for example.
+// Numbers are converted (as the arithmetic returns whatever kind
+// of number it needs), as are vectors and maps to []Any and
+// map[Any]Any, nil is the zero value.
+func As[T any](v Any) T {
+	switch t, ok := v.(T); {
+	case ok:
+		return t
+	case v == nil:
+		var zero T
+		return zero
+	}
+
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if n, ok := convertBig(v, typ); ok {
+		return n.(T)
+	}
+
+	if c, ok := convertColl(v, typ); ok {
+		return c.(T)
+	}
+
+	val := reflect.ValueOf(v)
+	if !isNumberKind(typ.Kind()) || !isNumberKind(val.Kind()) {
+		panic(fmt.Sprintf("can't use %T as %v", v, typ))
+	}
+
+	return val.Convert(typ).Interface().(T)
+}
+
+func isNumberKind(k reflect.Kind) bool {
+	return reflect.Int <= k && k <= reflect.Complex128
+}
@@ -0,0 +1,101 @@
+package core
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	xs := []int{1, 2}
+
+	if got := Store(&xs[0], 5); got != 5 || xs[0] != 5 {
+		t.Errorf("Store = %v, xs = %v", got, xs)
+	}
+
+	if got := Store(&xs[1], nil); got != 0 || xs[1] != 0 {
+		t.Errorf("Store(nil) = %v, xs = %v", got, xs)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("storing a string in an int didn't panic")
+		}
+	}()
+	Store(&xs[0], "a")
+}
+
+func TestStoreConverts(t *testing.T) {
+	var n int
+	if got := Store(&n, 2.0); got != 2 || n != 2 {
+		t.Errorf("Store(2.0) = %v, n = %v", got, n)
+	}
+
+	var f float64
+	if got := Store(&f, 3); got != 3.0 {
+		t.Errorf("Store(3) = %v", got)
+	}
+
+	var x Any = "a"
+	if got := Store(&x, 1); got != 1 || x != 1 {
+		t.Errorf("Store(&any, 1) = %v, x = %v", got, x)
+	}
+}
+
+func TestStoreLossy(t *testing.T) {
+	var n int
+	if Store(&n, big.NewRat(6, 3)); n != 2 {
+		t.Errorf("6/3 stored as %d", n)
+	}
+
+	lossy := []Any{2.75, big.NewRat(1, 2), new(big.Int).Lsh(big.NewInt(1), 70), math.NaN()}
+	for _, v := range lossy {
+		if recovered(func() { Store(&n, v) }) == nil {
+			t.Errorf("storing %v in an int didn't panic", v)
+		}
+	}
+}
+
+func TestAs(t *testing.T) {
+	if got := As[int](3.0); got != 3 {
+		t.Errorf("As[int](3.0) = %v", got)
+	}
+
+	if got := As[string](nil); got != "" {
+		t.Errorf("As[string](nil) = %q", got)
+	}
+
+	if got := As[[]int]([]int{1}); len(got) != 1 {
+		t.Errorf("As[[]int] = %v", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("As[int](\"a\") didn't panic")
+		}
+	}()
+	As[int]("a")
+}
+
+func TestAsBig(t *testing.T) {
+	if got := As[*big.Int](3); got.Cmp(big.NewInt(3)) != 0 {
+		t.Errorf("As[*big.Int](3) = %v", got)
+	}
+
+	if got := As[*big.Rat](0.5); got.Cmp(big.NewRat(1, 2)) != 0 {
+		t.Errorf("As[*big.Rat](0.5) = %v", got)
+	}
+
+	if got := As[int](big.NewInt(7)); got != 7 {
+		t.Errorf("As[int](7N) = %v", got)
+	}
+
+	if got := As[float64](big.NewRat(3, 4)); got != 0.75 {
+		t.Errorf("As[float64](3/4) = %v", got)
+	}
+
+	huge := new(big.Int).Lsh(big.NewInt(1), 70)
+	if recovered(func() { As[int](huge) }) == nil {
+		t.Errorf("As[int](%v) didn't panic", huge)
+	}
+}
@@ -0,0 +1,72 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Trampoline calls f with args and keeps calling the result for as
+// long as it is a func() Any, i.e. a (fn [] ...) without arguments.
+// That way mutually recursive functions can return their tail
+// calls as thunks and run in constant stack space.
+func Trampoline(f Any, args ...Any) Any {
+	ret := call(f, args...)
+
+	for {
+		thunk, ok := ret.(func() Any)
+		if !ok {
+			return ret
+		}
+
+		ret = thunk()
+	}
+}
+
+// Apply calls f with args, the last of which is a collection whose
+// elements are passed as arguments of their own: (apply f 1 [2 3])
+// is (f 1 2 3).
+func Apply(f Any, args ...Any) Any {
+	if len(args) == 0 {
+		return call(f)
+	}
+
+	last := len(args) - 1
+	return call(f, append(args[:last:last], NthRest(args[last], 0)...)...)
+}
+
+// call calls the function f, whatever its signature, with args.
+func call(f Any, args ...Any) Any {
+	if fn, ok := f.(func() Any); ok && len(args) == 0 {
+		return fn()
+	}
+
+	fn := reflect.ValueOf(f)
+	if fn.Kind() != reflect.Func {
+		panic(fmt.Sprintf("can't call %T", f))
+	}
+
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		var typ reflect.Type
+		if fn.Type().IsVariadic() && i >= fn.Type().NumIn()-1 {
+			typ = fn.Type().In(fn.Type().NumIn() - 1).Elem()
+		} else if i < fn.Type().NumIn() {
+			typ = fn.Type().In(i)
+		} else {
+			panic(fmt.Sprintf("too many arguments in call to %T", f))
+		}
+
+		if arg == nil {
+			in[i] = reflect.Zero(typ)
+		} else {
+			in[i] = reflect.ValueOf(arg)
+		}
+	}
+
+	out := fn.Call(in)
+	if len(out) == 0 {
+		return nil
+	}
+
+	return out[0].Interface()
+}
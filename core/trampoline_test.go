@@ -0,0 +1,85 @@
+package core
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCall(t *testing.T) {
+	tests := []struct {
+		f    Any
+		args []Any
+		want Any
+	}{
+		{func() Any { return 1 }, nil, 1},
+		{func(a, b Any) Any { return fmt.Sprint(a, b) }, []Any{1, 2}, "1 2"},
+		{func(a Any, xs ...Any) Any { return len(xs) }, []Any{1, 2, 3}, 2},
+		{func(s string) int { return len(s) }, []Any{"abc"}, 3},
+		{func(x Any) Any { return x }, []Any{nil}, nil},
+		{func() {}, nil, nil},
+	}
+
+	for i, test := range tests {
+		if got := call(test.f, test.args...); got != test.want {
+			t.Errorf("%d: call = %v, want %v", i, got, test.want)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	sum := func(xs ...Any) Any { return ADD(xs...) }
+
+	tests := []struct {
+		args []Any
+		want Any
+	}{
+		{[]Any{[]Any{1, 2}}, 3},
+		{[]Any{1, 2, []Any{3, 4}}, 10},
+		{[]Any{1, Vector(2, 3)}, 6},
+		{[]Any{1, nil}, 1},
+		{[]Any{[]int{1, 2}}, 3},
+		{nil, 0},
+	}
+
+	for _, test := range tests {
+		if got := Apply(sum, test.args...); !Equal(got, test.want) {
+			t.Errorf("(apply + %v) = %v, want %v", test.args, got, test.want)
+		}
+	}
+
+	args := []Any{1, []Any{2}}
+	if Apply(sum, args...); Count(args) != 2 || args[0] != 1 {
+		t.Errorf("Apply changed its arguments to %v", args)
+	}
+
+	if recovered(func() { Apply(sum, 1, 2) }) == nil {
+		t.Error("(apply + 1 2) didn't panic")
+	}
+}
+
+func TestCallPanics(t *testing.T) {
+	for _, f := range []Any{1, func(a Any) Any { return a }} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("call(%T, 1, 2) didn't panic", f)
+				}
+			}()
+			call(f, 1, 2)
+		}()
+	}
+}
+
+func TestTrampoline(t *testing.T) {
+	var countDown func(n Any) Any
+	countDown = func(n Any) Any {
+		if n.(int) == 0 {
+			return "done"
+		}
+		return func() Any { return countDown(n.(int) - 1) }
+	}
+
+	if got := Trampoline(countDown, 100000); got != "done" {
+		t.Errorf("Trampoline = %v, want done", got)
+	}
+}
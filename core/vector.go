@@ -0,0 +1,410 @@
+package core
+
+import (
+	"fmt"
+	"iter"
+	"strings"
+)
+
+// The persistent collections are tries of nodes 32 wide, indexed by
+// 5 bits of the index (or hash) per level.
+const (
+	trieBits  = 5
+	trieWidth = 1 << trieBits
+	trieMask  = trieWidth - 1
+)
+
+// owner marks the nodes a transient created, which it may change in
+// place. It must not be zero-sized, as pointers to those can be equal.
+type owner struct{ _ byte }
+
+type vnode struct {
+	edit  *owner
+	array [trieWidth]Any
+}
+
+// PersistentVector is what [...] literals evaluate to, an immutable
+// vector sharing its structure with the vectors it was made from, so
+// it is safe to pass around and share between goroutines. Conj and
+// Assoc return a new vector, copying only the path to the element.
+type PersistentVector struct {
+	count int
+	shift uint
+	root  *vnode
+	// the last (up to 32) elements, kept out of the trie
+	tail []Any
+}
+
+var (
+	emptyVNode  = &vnode{}
+	emptyVector = &PersistentVector{shift: trieBits, root: emptyVNode, tail: []Any{}}
+)
+
+// Vector returns a persistent vector of xs.
+func Vector(xs ...Any) *PersistentVector {
+	if len(xs) <= trieWidth {
+		tail := make([]Any, len(xs))
+		copy(tail, xs)
+		return &PersistentVector{count: len(xs), shift: trieBits, root: emptyVNode, tail: tail}
+	}
+
+	t := emptyVector.Transient()
+	for _, x := range xs {
+		t.Conj(x)
+	}
+
+	return t.Persistent()
+}
+
+func (v *PersistentVector) Count() int {
+	return v.count
+}
+
+// Nth returns the i-th element, panicking if it is out of range.
+func (v *PersistentVector) Nth(i int) Any {
+	if i < 0 || i >= v.count {
+		panic(fmt.Sprintf("index %d out of range for vector of length %d", i, v.count))
+	}
+
+	return v.arrayFor(i)[i&trieMask]
+}
+
+// Peek returns the last element, nil if v is empty.
+func (v *PersistentVector) Peek() Any {
+	if v.count == 0 {
+		return nil
+	}
+
+	return v.tail[len(v.tail)-1]
+}
+
+func (v *PersistentVector) tailOffset() int {
+	if v.count < trieWidth {
+		return 0
+	}
+
+	return ((v.count - 1) >> trieBits) << trieBits
+}
+
+func (v *PersistentVector) arrayFor(i int) []Any {
+	if i >= v.tailOffset() {
+		return v.tail
+	}
+
+	node := v.root
+	for level := v.shift; level > 0; level -= trieBits {
+		node = node.array[(i>>level)&trieMask].(*vnode)
+	}
+
+	return node.array[:]
+}
+
+// Conj returns a vector with x added to the end.
+func (v *PersistentVector) Conj(x Any) *PersistentVector {
+	if v.count-v.tailOffset() < trieWidth {
+		// the full slice expression makes append copy the tail
+		tail := append(v.tail[:len(v.tail):len(v.tail)], x)
+		return &PersistentVector{count: v.count + 1, shift: v.shift, root: v.root, tail: tail}
+	}
+
+	// the tail is full, it moves into the trie
+	tailNode := &vnode{}
+	copy(tailNode.array[:], v.tail)
+
+	root, shift := v.root, v.shift
+	if v.count>>trieBits > 1<<v.shift {
+		// no room left under the root
+		root = &vnode{}
+		root.array[0] = v.root
+		root.array[1] = newPath(nil, v.shift, tailNode)
+		shift += trieBits
+	} else {
+		root = pushTail(nil, v.count, v.shift, v.root, tailNode)
+	}
+
+	return &PersistentVector{count: v.count + 1, shift: shift, root: root, tail: []Any{x}}
+}
+
+// Assoc returns a vector with the i-th element replaced by x, or x
+// added to the end when i is the length of v.
+func (v *PersistentVector) Assoc(i int, x Any) *PersistentVector {
+	switch {
+	case i == v.count:
+		return v.Conj(x)
+	case i < 0 || i > v.count:
+		panic(fmt.Sprintf("index %d out of range for vector of length %d", i, v.count))
+	case i >= v.tailOffset():
+		tail := make([]Any, len(v.tail))
+		copy(tail, v.tail)
+		tail[i&trieMask] = x
+		return &PersistentVector{count: v.count, shift: v.shift, root: v.root, tail: tail}
+	}
+
+	return &PersistentVector{count: v.count, shift: v.shift, root: assocPath(nil, v.shift, v.root, i, x), tail: v.tail}
+}
+
+// Pop returns a vector without the last element.
+func (v *PersistentVector) Pop() *PersistentVector {
+	switch {
+	case v.count == 0:
+		panic("can't pop an empty vector")
+	case v.count == 1:
+		return emptyVector
+	case v.count-v.tailOffset() > 1:
+		return &PersistentVector{count: v.count - 1, shift: v.shift, root: v.root, tail: v.tail[:len(v.tail)-1]}
+	}
+
+	// the tail becomes empty, the last leaf of the trie replaces it
+	tail := v.arrayFor(v.count - 2)
+	root, shift := popTail(v.count, v.shift, v.root), v.shift
+	if root == nil {
+		root = emptyVNode
+	}
+	if shift > trieBits && root.array[1] == nil {
+		root = root.array[0].(*vnode)
+		shift -= trieBits
+	}
+
+	return &PersistentVector{count: v.count - 1, shift: shift, root: root, tail: tail}
+}
+
+// All iterates over the indices and elements of v.
+func (v *PersistentVector) All() iter.Seq2[int, Any] {
+	return func(yield func(int, Any) bool) {
+		for i := 0; i < v.count; i += trieWidth {
+			for j, x := range v.arrayFor(i) {
+				if i+j >= v.count || !yield(i+j, x) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Slice returns the elements of v as a new []Any.
+func (v *PersistentVector) Slice() []Any {
+	out := make([]Any, 0, v.count)
+	for _, x := range v.All() {
+		out = append(out, x)
+	}
+
+	return out
+}
+
+func (v *PersistentVector) String() string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, x := range v.All() {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		fmt.Fprint(&b, x)
+	}
+	b.WriteByte(']')
+
+	return b.String()
+}
+
+// Transient returns a transient copy of v, to conj many elements
+// onto without copying any path more than once.
+func (v *PersistentVector) Transient() *TransientVector {
+	edit := &owner{}
+	tail := make([]Any, trieWidth)
+	copy(tail, v.tail)
+
+	return &TransientVector{
+		count: v.count,
+		shift: v.shift,
+		root:  &vnode{edit: edit, array: v.root.array},
+		tail:  tail,
+		edit:  edit,
+	}
+}
+
+// editable returns node, if edit owns it, or a copy owned by edit.
+// Persistent changes pass a nil edit, copying every node.
+func editable(edit *owner, node *vnode) *vnode {
+	if edit != nil && node.edit == edit {
+		return node
+	}
+
+	return &vnode{edit: edit, array: node.array}
+}
+
+func newPath(edit *owner, level uint, node *vnode) *vnode {
+	if level == 0 {
+		return node
+	}
+
+	path := &vnode{edit: edit}
+	path.array[0] = newPath(edit, level-trieBits, node)
+	return path
+}
+
+// pushTail adds tailNode as the last leaf of the trie under parent,
+// count being the number of elements before adding it.
+func pushTail(edit *owner, count int, level uint, parent, tailNode *vnode) *vnode {
+	node := editable(edit, parent)
+	i := ((count - 1) >> level) & trieMask
+
+	switch child, _ := parent.array[i].(*vnode); {
+	case level == trieBits:
+		node.array[i] = tailNode
+	case child != nil:
+		node.array[i] = pushTail(edit, count, level-trieBits, child, tailNode)
+	default:
+		node.array[i] = newPath(edit, level-trieBits, tailNode)
+	}
+
+	return node
+}
+
+func assocPath(edit *owner, level uint, parent *vnode, i int, x Any) *vnode {
+	node := editable(edit, parent)
+	if level == 0 {
+		node.array[i&trieMask] = x
+		return node
+	}
+
+	sub := (i >> level) & trieMask
+	node.array[sub] = assocPath(edit, level-trieBits, parent.array[sub].(*vnode), i, x)
+	return node
+}
+
+// popTail removes the last leaf of the trie, returning nil when the
+// node is left empty.
+func popTail(count int, level uint, parent *vnode) *vnode {
+	i := ((count - 2) >> level) & trieMask
+
+	if level > trieBits {
+		child := popTail(count, level-trieBits, parent.array[i].(*vnode))
+		if child == nil && i == 0 {
+			return nil
+		}
+
+		node := editable(nil, parent)
+		if child == nil {
+			node.array[i] = nil
+		} else {
+			node.array[i] = child
+		}
+		return node
+	}
+
+	if i == 0 {
+		return nil
+	}
+
+	node := editable(nil, parent)
+	node.array[i] = nil
+	return node
+}
+
+// TransientVector builds a vector in place, it is not safe to use
+// from several goroutines nor after Persistent is called.
+type TransientVector struct {
+	count int
+	shift uint
+	root  *vnode
+	// always 32 long, holding count-tailOffset elements
+	tail []Any
+	edit *owner
+}
+
+func (t *TransientVector) ensureEditable() {
+	if t.edit == nil {
+		panic("transient used after persistent!")
+	}
+}
+
+func (t *TransientVector) Count() int {
+	t.ensureEditable()
+	return t.count
+}
+
+func (t *TransientVector) tailOffset() int {
+	if t.count < trieWidth {
+		return 0
+	}
+
+	return ((t.count - 1) >> trieBits) << trieBits
+}
+
+// Nth returns the i-th element, panicking if it is out of range.
+func (t *TransientVector) Nth(i int) Any {
+	t.ensureEditable()
+	if i < 0 || i >= t.count {
+		panic(fmt.Sprintf("index %d out of range for vector of length %d", i, t.count))
+	}
+
+	if i >= t.tailOffset() {
+		return t.tail[i&trieMask]
+	}
+
+	node := t.root
+	for level := t.shift; level > 0; level -= trieBits {
+		node = node.array[(i>>level)&trieMask].(*vnode)
+	}
+
+	return node.array[i&trieMask]
+}
+
+// Conj adds x to the end of t.
+func (t *TransientVector) Conj(x Any) *TransientVector {
+	t.ensureEditable()
+
+	if i := t.count; i-t.tailOffset() < trieWidth {
+		t.tail[i&trieMask] = x
+		t.count++
+		return t
+	}
+
+	tailNode := &vnode{edit: t.edit}
+	copy(tailNode.array[:], t.tail)
+	t.tail = make([]Any, trieWidth)
+	t.tail[0] = x
+
+	if t.count>>trieBits > 1<<t.shift {
+		root := &vnode{edit: t.edit}
+		root.array[0] = t.root
+		root.array[1] = newPath(t.edit, t.shift, tailNode)
+		t.root = root
+		t.shift += trieBits
+	} else {
+		t.root = pushTail(t.edit, t.count, t.shift, t.root, tailNode)
+	}
+
+	t.count++
+	return t
+}
+
+// Assoc replaces the i-th element by x, or adds x to the end when i
+// is the length of t.
+func (t *TransientVector) Assoc(i int, x Any) *TransientVector {
+	t.ensureEditable()
+
+	switch {
+	case i == t.count:
+		return t.Conj(x)
+	case i < 0 || i > t.count:
+		panic(fmt.Sprintf("index %d out of range for vector of length %d", i, t.count))
+	case i >= t.tailOffset():
+		t.tail[i&trieMask] = x
+	default:
+		t.root = assocPath(t.edit, t.shift, t.root, i, x)
+	}
+
+	return t
+}
+
+// Persistent returns the vector built so far, t can't be used any
+// further.
+func (t *TransientVector) Persistent() *PersistentVector {
+	t.ensureEditable()
+	t.edit = nil
+
+	tail := make([]Any, t.count-t.tailOffset())
+	copy(tail, t.tail)
+
+	return &PersistentVector{count: t.count, shift: t.shift, root: t.root, tail: tail}
+}
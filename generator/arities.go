@@ -0,0 +1,129 @@
+package generator
+
+import (
+	"github.com/jcla1/gisp/parser"
+	h "github.com/jcla1/gisp/generator/helpers"
+	"go/ast"
+	"go/token"
+)
+
+// (fn ([x] ...) ([x y] ...) ([x y & more] ...))
+func checkMultiArityFuncArgs(node *parser.CallNode) bool {
+	if !isCallTo(node, "fn") || len(node.Args) < 1 {
+		return false
+	}
+
+	for _, arity := range node.Args {
+		clause, ok := arity.(*parser.CallNode)
+		if !ok || clause.Callee.Type() != parser.NodeVector {
+			return false
+		}
+
+		if len(clause.Args) < 1 {
+			panic("every arity of a fn needs at least one expression!")
+		}
+	}
+
+	return true
+}
+
+// getArity returns the number of fixed parameters and whether
+// there is a rest parameter.
+func getArity(params *parser.VectorNode) (int, bool) {
+	for i, param := range params.Nodes {
+		if isIdent(param, "&") {
+			return i, true
+		}
+	}
+
+	return len(params.Nodes), false
+}
+
+// makeMultiArityFunc generates a variadic Go function, dispatching
+// to one fn per arity on the number of arguments:
+//
+//	func(GEN0 ...core.Any) core.Any {
+//		switch {
+//		case len(GEN0) == 1:
+//			return func(x core.Any) core.Any { ... }(GEN0[0])
+//		...
+//		default:
+//			panic(core.ArityError(len(GEN0)))
+//		}
+//	}
+func makeMultiArityFunc(node *parser.CallNode, self string) *ast.FuncLit {
+	args := generateIdent()
+	numArgs := makeFuncCall(ast.NewIdent("len"), h.E(args))
+
+	fixed := map[int]bool{}
+	maxFixed, restArity := -1, -1
+	var restClause *ast.CaseClause
+
+	clauses := h.EmptyS()
+
+	for _, arity := range node.Args {
+		clause := arity.(*parser.CallNode)
+		params := clause.Callee.(*parser.VectorNode)
+
+		fnNode := &parser.CallNode{NodeType: parser.NodeCall, Callee: parser.NewIdentNode("fn"), Args: append([]parser.Node{params}, clause.Args...)}
+		if !checkFuncArgs(fnNode) {
+			panic("invalid fn arity: " + params.String())
+		}
+
+		n, variadic := getArity(params)
+
+		callArgs := h.EmptyE()
+		for i := 0; i < n; i++ {
+			callArgs = append(callArgs, &ast.IndexExpr{X: args, Index: makeIntLit(i)})
+		}
+
+		call := makeFuncCall(makeFunc(fnNode, self), callArgs)
+
+		var cond ast.Expr
+		if variadic {
+			if restClause != nil {
+				panic("a fn can only have one variadic arity!")
+			}
+
+			call.Args = append(call.Args, &ast.SliceExpr{X: args, Low: makeIntLit(n)})
+			call.Ellipsis = token.Pos(1)
+
+			cond = makeBinaryExpr(token.GEQ, numArgs, makeIntLit(n))
+			restArity = n
+		} else {
+			if fixed[n] {
+				panic("a fn can't have two arities with the same number of parameters!")
+			}
+
+			fixed[n] = true
+			maxFixed = max(maxFixed, n)
+
+			cond = makeBinaryExpr(token.EQL, numArgs, makeIntLit(n))
+		}
+
+		caseClause := makeCaseClause(h.E(cond), h.S(makeReturnStmt(h.E(call))))
+		if variadic {
+			restClause = caseClause
+		} else {
+			clauses = append(clauses, caseClause)
+		}
+	}
+
+	// The variadic arity has to come last, so it doesn't
+	// shadow any of the fixed ones.
+	if restClause != nil {
+		if restArity < maxFixed {
+			panic("a fn can't have a fixed arity with more parameters than the variadic one!")
+		}
+
+		clauses = append(clauses, restClause)
+	}
+
+	arityErr := makeFuncCall(makeSelectorExpr(ast.NewIdent("core"), ast.NewIdent("ArityError")), h.E(numArgs))
+	clauses = append(clauses, makeCaseClause(nil, h.S(makeExprStmt(makeFuncCall(ast.NewIdent("panic"), h.E(arityErr))))))
+
+	params := makeFieldList([]*ast.Field{makeField(h.I(args), makeEllipsis(anyType))})
+	results := makeFieldList([]*ast.Field{makeField(nil, anyType)})
+
+	return makeFuncLit(makeFuncType(results, params), makeBlockStmt(h.S(makeSwitchStmt(nil, makeBlockStmt(clauses)))))
+}
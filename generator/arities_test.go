@@ -0,0 +1,29 @@
+package generator
+
+import "testing"
+
+func TestArities(t *testing.T) {
+	runCompileTests(t, []compileTest{
+		{"multi-arity", `(def f (fn ([] 0) ([x] x) ([x y] (+ x y))))`, ""},
+		{"variadic arity", `(def f (fn ([x] x) ([x & more] (fmt/sprint x more))))`, ""},
+		{"call", `(def f (fn ([x] x) ([x y] (+ x y))))
+(def g (fn [] (+ (f 1) (f 1 2))))`, ""},
+		{"destructured arity", `(def f (fn ([[a b]] (+ a b)) ([x y] (+ x y))))`, ""},
+		{"no body", `(def f (fn ([x]) ([x y] y)))`, "at least one expression"},
+		{"bad params", `(def f (fn ([x "y"] x) ([x y z] y)))`, "invalid fn arity"},
+		{"two variadic", `(def f (fn ([& xs] xs) ([x & xs] xs)))`, "only have one variadic arity"},
+		{"same arity", `(def f (fn ([x] x) ([y] y)))`, "same number of parameters"},
+		{"fixed longer than variadic", `(def f (fn ([x y z] x) ([x & xs] xs)))`, "more parameters than the variadic one"},
+	})
+}
+
+func TestKeywordArgs(t *testing.T) {
+	runCompileTests(t, []compileTest{
+		{"keys", `(def f (fn [x & {:keys [y z]}] (fmt/sprint x y z)))
+(def g (fn [] (f 1 :y 2)))`, ""},
+		{"defaults", `(def f (fn [x & {:keys [y] :or {y 10}}] (+ x y)))`, ""},
+		{"keyword value", `(def f (fn [] :a))`, ""},
+		{"bad or", `(def f (fn [x & {:keys [y] :or [y 1]}] y))`, ":or needs a map"},
+		{"or non ident", `(def f (fn [x & {:keys [y] :or {"y" 1}}] y))`, ":or needs a map"},
+	})
+}
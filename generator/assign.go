@@ -0,0 +1,147 @@
+package generator
+
+import (
+	"github.com/jcla1/gisp/parser"
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// The names bound by the enclosing fns, lets and loops, the
+// innermost scope last.
+var localScopes [][]string
+
+// The def'd names of the package, true for those that are fns.
+var packageDefs map[string]bool
+
+func pushLocals(names []string) {
+	localScopes = append(localScopes, names)
+}
+
+// addLocals brings more names into the innermost scope.
+func addLocals(names []string) {
+	top := len(localScopes) - 1
+	localScopes[top] = append(localScopes[top], names...)
+}
+
+func popLocals() {
+	localScopes = localScopes[:len(localScopes)-1]
+}
+
+func isLocal(name string) bool {
+	for _, scope := range localScopes {
+		if isInSlice(name, scope) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func checkSetBangArgs(node *parser.CallNode) bool {
+	if !isCallTo(node, "set!") {
+		return false
+	}
+
+	if len(node.Args) != 2 {
+		panic("set! needs a place and a value!")
+	}
+
+	return true
+}
+
+// set! stores the value in a local, a package variable, a field
+// (p/x), an element (aget xs i) or where a pointer points (* p).
+// The value is converted to the place's type by core.Store.
+func makeSetBang(node *parser.CallNode) *ast.CallExpr {
+	return coreCall("Store", makePlacePointer(node.Args[0]), EvalExpr(node.Args[1]))
+}
+
+// setLocals returns the Go names of the locals that nodes set!.
+func setLocals(nodes []parser.Node) map[string]bool {
+	names := map[string]bool{}
+
+	var walk func(nodes []parser.Node)
+	walk = func(nodes []parser.Node) {
+		for _, node := range nodes {
+			switch n := node.(type) {
+			case *parser.CallNode:
+				if isCallTo(n, "set!") && len(n.Args) > 0 {
+					if ident, ok := n.Args[0].(*parser.IdentNode); ok {
+						names[makeLocalIdent(ident.Ident).Name] = true
+					}
+				}
+				walk(append([]parser.Node{n.Callee}, n.Args...))
+			case *parser.VectorNode:
+				walk(n.Nodes)
+			case *parser.MapNode:
+				walk(n.Nodes)
+			case *parser.SetNode:
+				walk(n.Nodes)
+			}
+		}
+	}
+	walk(nodes)
+
+	return names
+}
+
+// declareAsAny turns the bindings x := v of the names isAny picks
+// into var x core.Any = v, so that later assignments aren't held to
+// the type of the first value: (let [[x 1]] (set! x 2.5) x) is 2.5.
+func declareAsAny(stmts []ast.Stmt, isAny func(string) bool) []ast.Stmt {
+	for i, stmt := range stmts {
+		assign, ok := stmt.(*ast.AssignStmt)
+		if !ok || assign.Tok != token.DEFINE {
+			continue
+		}
+
+		names := make([]*ast.Ident, 0, len(assign.Lhs))
+		found := false
+		for _, lhs := range assign.Lhs {
+			ident := lhs.(*ast.Ident)
+			names = append(names, ident)
+			found = found || isAny(ident.Name)
+		}
+
+		if found {
+			spec := makeValueSpec(names, assign.Rhs, anyType)
+			stmts[i] = makeDeclStmt(makeGeneralDecl(token.VAR, []ast.Spec{spec}))
+		}
+	}
+
+	return stmts
+}
+
+func makePlacePointer(node parser.Node) ast.Expr {
+	switch n := node.(type) {
+	case *parser.IdentNode:
+		if strings.Contains(n.Ident, "/") {
+			return makeUnaryExpr(token.AND, makeIdomaticSelector(n.Ident))
+		}
+
+		isFn, isDef := packageDefs[n.Ident]
+		switch {
+		case isLocal(n.Ident):
+		case isFn:
+			panic("can't set! " + n.Ident + ", it isn't a variable!")
+		case !isDef:
+			panic("can't set! unknown name: " + n.Ident)
+		}
+
+		return makeUnaryExpr(token.AND, makeIdomaticIdent(n.Ident))
+
+	case *parser.CallNode:
+		switch {
+		case isCallTo(n, "*") && len(n.Args) == 1:
+			return EvalExpr(n.Args[0])
+
+		case isCallTo(n, "aget") && checkSliceArgs(n):
+			return makeUnaryExpr(token.AND, makeSliceForm(n))
+		}
+
+		panic("can't set! " + n.String())
+	}
+
+	panic("can't set! a constant: " + node.String())
+}
@@ -0,0 +1,24 @@
+package generator
+
+import "testing"
+
+func TestSetBang(t *testing.T) {
+	runCompileTests(t, []compileTest{
+		{"local", `(def f (fn [x] (set! x (+ x 1)) x))`, ""},
+		{"let", `(def f (fn [] (let [[n 0]] (dotimes [i 3] (set! n i)) n)))`, ""},
+		{"closure", `(def f (fn [] (let [[n 0] [inc (fn [] (set! n (+ n 1)))]] (inc) n)))`, ""},
+		{"let any value", `(def f (fn [] (let [[x 1]] (set! x 2.5) (set! x "s") x)))`, ""},
+		{"let in closure", `(def f (fn [] (let [[x 1] [g (fn [] (set! x 2.5))]] (g) x)))`, ""},
+		{"package def", `(def counter 0)
+(def f (fn [] (set! counter (+ counter 1))))`, ""},
+		{"selector", `(def f (fn [] (set! os/args (vec string "gisp"))))`, ""},
+		{"element", `(def f (fn [v] (let [[xs (make (slice int) 2)]] (set! (aget xs 1) v) xs)))`, ""},
+		{"pointer", `(def f (fn [v] (let [[p (new string)]] (set! (* p) v) p)))`, ""},
+		{"fn", `(def g (fn [] 1))
+(def f (fn [] (set! g 2)))`, "can't set! g, it isn't a variable"},
+		{"unknown", `(def f (fn [] (set! nope 2)))`, "can't set! unknown name: nope"},
+		{"constant", `(def f (fn [] (set! 1 2)))`, "can't set! a constant"},
+		{"call", `(def f (fn [x] (set! (g x) 2)))`, "can't set! (g x)"},
+		{"args", `(def f (fn [p] (set! (* p))))`, "set! needs a place and a value"},
+	})
+}
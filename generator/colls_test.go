@@ -0,0 +1,15 @@
+package generator
+
+import "testing"
+
+func TestPersistentColls(t *testing.T) {
+	runCompileTests(t, []compileTest{
+		{"literals", `(def f (fn [] (fmt/sprint [1 2] {:a 1} #{1 2})))`, ""},
+		{"constructors", `(def f (fn [] (fmt/sprint (vector 1 2) (hash-map :a 1) (sorted-map 2 1) (hash-set 1) (sorted-set 2 1))))`, ""},
+		{"updates", `(def f (fn [v m s] (fmt/sprint (conj v 3) (assoc m :b 2) (dissoc m :a) (disj s 1))))`, ""},
+		{"lookups", `(def f (fn [m] (fmt/sprint (get :a m) (contains? m :a) (count m))))`, ""},
+		{"transients", `(def f (fn [v m s] (fmt/sprint (persistent! (conj! (transient v) 1)) (assoc! (transient m) :a 1) (dissoc! (transient m) :a) (disj! (transient s) 1))))`, ""},
+		{"destructured", `(def f (fn [[a b & more]] (fmt/sprint a b (count more))))`, ""},
+		{"in doseq", `(def f (fn [] (doseq [x [1 2 3]] (fmt/sprint x))))`, ""},
+	})
+}
@@ -0,0 +1,185 @@
+package generator
+
+import (
+	"github.com/jcla1/gisp/parser"
+	h "github.com/jcla1/gisp/generator/helpers"
+	"fmt"
+	"go/ast"
+	"go/token"
+)
+
+func isCallTo(node *parser.CallNode, name string) bool {
+	if node.Callee.Type() != parser.NodeIdent {
+		return false
+	}
+
+	return node.Callee.(*parser.IdentNode).Ident == name
+}
+
+func isElseKeyword(node parser.Node) bool {
+	return isKeywordNode(node, "else")
+}
+
+func checkCondArgs(node *parser.CallNode) bool {
+	if !isCallTo(node, "cond") {
+		return false
+	}
+
+	if len(node.Args)%2 != 0 {
+		panic("cond needs an even number of test/expression pairs!")
+	}
+
+	for i := 0; i < len(node.Args); i += 2 {
+		if isElseKeyword(node.Args[i]) && i != len(node.Args)-2 {
+			panic("cond's :else has to be the last clause!")
+		}
+	}
+
+	return true
+}
+
+// cond compiles to a tagless switch. Without an :else clause
+// the default case returns nil, so the switch is exhaustive.
+func makeCondFunc(node *parser.CallNode) *ast.CallExpr {
+	clauses := h.EmptyS()
+	hasDefault := false
+
+	for i := 0; i < len(node.Args); i += 2 {
+		var list []ast.Expr
+		if isElseKeyword(node.Args[i]) {
+			hasDefault = true
+		} else {
+			list = h.E(EvalExpr(node.Args[i]))
+		}
+
+		clauses = append(clauses, makeCaseClause(list, h.S(makeReturnStmt(h.E(EvalExpr(node.Args[i+1]))))))
+	}
+
+	if !hasDefault {
+		clauses = append(clauses, makeCaseClause(nil, h.S(makeReturnStmt(h.E(ast.NewIdent("nil"))))))
+	}
+
+	return makeClosureCall(h.S(makeSwitchStmt(nil, makeBlockStmt(clauses))))
+}
+
+func checkCaseArgs(node *parser.CallNode) bool {
+	if !isCallTo(node, "case") {
+		return false
+	}
+
+	if len(node.Args) < 1 || len(node.Args)%2 != 1 {
+		panic("case needs an expression followed by constant/expression pairs!")
+	}
+
+	for i := 1; i < len(node.Args); i += 2 {
+		if isElseKeyword(node.Args[i]) {
+			if i != len(node.Args)-2 {
+				panic("case's :else has to be the last clause!")
+			}
+			continue
+		}
+
+		for _, c := range getCaseConstants(node.Args[i]) {
+			if ident, ok := c.(*parser.IdentNode); ok {
+				panic(fmt.Sprintf("case can only dispatch on constants, not on %s!", ident.Ident))
+			}
+			if !isConstantNode(c) {
+				panic("case can only dispatch on constants!")
+			}
+		}
+	}
+
+	return true
+}
+
+// A list of constants, i.e. (1 2 3), shares one clause
+func getCaseConstants(node parser.Node) []parser.Node {
+	if call, ok := node.(*parser.CallNode); ok {
+		return append([]parser.Node{call.Callee}, call.Args...)
+	}
+
+	return []parser.Node{node}
+}
+
+// Only literals and keywords are constants, an identifier could be
+// bound to anything.
+func isConstantNode(node parser.Node) bool {
+	switch node.Type() {
+	case parser.NodeNumber, parser.NodeString, parser.NodeKeyword, parser.NodeChar, parser.NodeBool, parser.NodeNil:
+		return true
+	}
+
+	return false
+}
+
+func makeCaseFunc(node *parser.CallNode) *ast.CallExpr {
+	clauses := h.EmptyS()
+	hasDefault := false
+
+	for i := 1; i < len(node.Args); i += 2 {
+		var list []ast.Expr
+		if isElseKeyword(node.Args[i]) {
+			hasDefault = true
+		} else {
+			list = EvalExprs(getCaseConstants(node.Args[i]))
+		}
+
+		clauses = append(clauses, makeCaseClause(list, h.S(makeReturnStmt(h.E(EvalExpr(node.Args[i+1]))))))
+	}
+
+	if !hasDefault {
+		clauses = append(clauses, makeCaseClause(nil, h.S(makeReturnStmt(h.E(ast.NewIdent("nil"))))))
+	}
+
+	switchStmt := makeSwitchStmt(EvalExpr(node.Args[0]), makeBlockStmt(clauses))
+	return makeClosureCall(h.S(switchStmt))
+}
+
+func checkWhenArgs(node *parser.CallNode) bool {
+	if !isCallTo(node, "when") && !isCallTo(node, "unless") {
+		return false
+	}
+
+	if len(node.Args) < 2 {
+		panic("when/unless need a test and at least one expression!")
+	}
+
+	return true
+}
+
+// when and unless evaluate their body only if the test
+// is true (or false respectively), otherwise they return nil.
+func makeWhenFunc(node *parser.CallNode) *ast.CallExpr {
+	cond := EvalExpr(node.Args[0])
+	if isCallTo(node, "unless") {
+		cond = makeUnaryExpr(token.NOT, cond)
+	}
+
+	ifStmt := makeIfStmt(cond, makeFuncBody(EvalExprs(node.Args[1:])), nil)
+	return makeClosureCall(h.S(ifStmt, makeReturnStmt(h.E(ast.NewIdent("nil")))))
+}
+
+func checkDoArgs(node *parser.CallNode) bool {
+	if !isCallTo(node, "do") {
+		return false
+	}
+
+	if len(node.Args) < 1 {
+		panic("do needs at least one expression!")
+	}
+
+	return true
+}
+
+func makeDoFunc(node *parser.CallNode) *ast.CallExpr {
+	return makeClosureCall(makeFuncBody(EvalExprs(node.Args)).List)
+}
+
+// makeClosureCall wraps the statements into a
+// func() core.Any { ... } and calls it immediately.
+func makeClosureCall(body []ast.Stmt) *ast.CallExpr {
+	returnList := makeFieldList([]*ast.Field{makeField(nil, anyType)})
+	fn := makeFuncLit(makeFuncType(returnList, nil), makeBlockStmt(body))
+
+	return makeFuncCall(fn, h.EmptyE())
+}
@@ -0,0 +1,39 @@
+package generator
+
+import "testing"
+
+func TestCond(t *testing.T) {
+	runCompileTests(t, []compileTest{
+		{"cond", `(def f (fn [x] (cond (> x 1) "big" (< x 0) "negative" :else "small")))`, ""},
+		{"no else", `(def f (fn [x] (cond (> x 1) "big")))`, ""},
+		{"odd", `(def f (fn [x] (cond (> x 1))))`, "even number"},
+		{"call as constants", `(def f (fn [x] (case x (+ 1 2) "three")))`, "not on +"},
+		{"ident", `(def f (fn [x y] (case x y "same")))`, "not on y"},
+		{"keywords and literals", `(def f (fn [x] (case x :a 1 \b 2 "c" 3 (true nil) 4)))`, ""},
+		{"else not last", `(def f (fn [x] (cond :else 1 (> x 1) 2)))`, "last clause"},
+	})
+}
+
+func TestCase(t *testing.T) {
+	runCompileTests(t, []compileTest{
+		{"case", `(def f (fn [x] (case x 1 "one" "two" 2 :else "many")))`, ""},
+		{"shared clause", `(def f (fn [x] (case x (1 2 3) "small" :else "big")))`, ""},
+		{"no else", `(def f (fn [x] (case x 1 "one")))`, ""},
+		{"no pairs", `(def f (fn [x] (case x 1)))`, "constant/expression pairs"},
+		{"not constant", `(def f (fn [x] (case x [1] "one")))`, "only dispatch on constants"},
+		{"call as constants", `(def f (fn [x] (case x (+ 1 2) "three")))`, "not on +"},
+		{"ident", `(def f (fn [x y] (case x y "same")))`, "not on y"},
+		{"keywords and literals", `(def f (fn [x] (case x :a 1 \b 2 "c" 3 (true nil) 4)))`, ""},
+		{"else not last", `(def f (fn [x] (case x :else 1 2 3)))`, "last clause"},
+	})
+}
+
+func TestWhenUnlessDo(t *testing.T) {
+	runCompileTests(t, []compileTest{
+		{"when", `(def f (fn [x] (when (> x 1) (fmt/println x) x)))`, ""},
+		{"unless", `(def f (fn [x] (unless (> x 1) x)))`, ""},
+		{"do", `(def f (fn [x] (do (fmt/println x) x)))`, ""},
+		{"empty when", `(def f (fn [x] (when (> x 1))))`, "at least one expression"},
+		{"empty do", `(def f (fn [x] (do)))`, "at least one expression"},
+	})
+}
@@ -0,0 +1,203 @@
+package generator
+
+import (
+	"github.com/jcla1/gisp/parser"
+	h "github.com/jcla1/gisp/generator/helpers"
+	"go/ast"
+)
+
+// splitTypeHints takes the ^T hints out of a parameter (or field)
+// vector, returning the nodes without them and the type each one
+// was given, nil meaning core.Any.
+func splitTypeHints(nodes []parser.Node) ([]parser.Node, []ast.Expr) {
+	plain := []parser.Node{}
+	types := []ast.Expr{}
+
+	for _, node := range nodes {
+		hint, ok := node.(*parser.TypeHintNode)
+		if !ok {
+			plain = append(plain, node)
+			types = append(types, nil)
+			continue
+		}
+
+		last := len(plain) - 1
+		if last < 0 || isIdent(plain[last], "&") || types[last] != nil {
+			panic("a type hint has to follow the name it applies to: " + hint.String())
+		}
+		types[last] = evalType(hint)
+	}
+
+	return plain, types
+}
+
+// stripFuncTypes takes the types out of a typed fn, like
+// (fn [xs ^[]int & more ^int] ^int ...). It returns the plain fn,
+// the types of the parameters (the rest parameter's last) and the
+// result type, nil always meaning core.Any.
+func stripFuncTypes(node *parser.CallNode) (*parser.CallNode, []ast.Expr, ast.Expr) {
+	vect := node.Args[0].(*parser.VectorNode)
+	params, hinted := splitTypeHints(vect.Nodes)
+
+	types := []ast.Expr{}
+	for i, param := range params {
+		if !isIdent(param, "&") {
+			types = append(types, hinted[i])
+		}
+	}
+
+	body := node.Args[1:]
+	var result ast.Expr
+	if hint, ok := body[0].(*parser.TypeHintNode); ok {
+		result = evalType(hint)
+		body = body[1:]
+	}
+
+	if len(params) == len(vect.Nodes) && result == nil {
+		return node, types, nil
+	}
+
+	plainParams := &parser.VectorNode{NodeType: parser.NodeVector, Nodes: params}
+	stripped := &parser.CallNode{NodeType: parser.NodeCall, Callee: node.Callee, Args: append([]parser.Node{plainParams}, body...)}
+
+	return stripped, types, result
+}
+
+// makeParamFields declares the parameters, grouping the untyped
+// ones: func(a, b core.Any, xs []int)
+func makeParamFields(idents []*ast.Ident, types []ast.Expr) []*ast.Field {
+	fields := []*ast.Field{}
+
+	for i, ident := range idents {
+		if types[i] == nil && i > 0 && types[i-1] == nil {
+			last := fields[len(fields)-1]
+			last.Names = append(last.Names, ident)
+			continue
+		}
+
+		typ := types[i]
+		if typ == nil {
+			typ = anyType
+		}
+		fields = append(fields, makeField(h.I(ident), typ))
+	}
+
+	return fields
+}
+
+// core.As[T](x)
+func makeAs(typ, x ast.Expr) *ast.CallExpr {
+	as := &ast.IndexExpr{X: makeSelectorExpr(ast.NewIdent("core"), ast.NewIdent("As")), Index: typ}
+	return makeFuncCall(as, h.E(x))
+}
+
+// convertReturns makes the results of a typed fn (the generated code
+// being core.Any) its result type, leaving nested fns alone.
+func convertReturns(body *ast.BlockStmt, typ ast.Expr) {
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ReturnStmt:
+			for i, result := range n.Results {
+				if _, ok := result.(*ast.BasicLit); !ok {
+					n.Results[i] = makeAs(typ, result)
+				}
+			}
+		}
+		return true
+	})
+}
+
+// [T comparable U any] becomes [T comparable, U any]
+func makeTypeParams(vect *parser.VectorNode) *ast.FieldList {
+	if len(vect.Nodes) == 0 || len(vect.Nodes)%2 != 0 {
+		panic("type parameters should look like: [T constraint ...]")
+	}
+
+	fields := []*ast.Field{}
+	for i := 0; i < len(vect.Nodes); i += 2 {
+		name, ok := vect.Nodes[i].(*parser.IdentNode)
+		if !ok {
+			panic("type parameters should look like: [T constraint ...]")
+		}
+
+		fields = append(fields, makeField(h.I(makeLocalIdent(name.Ident)), evalType(vect.Nodes[i+1])))
+	}
+
+	return makeFieldList(fields)
+}
+
+// The type parameters as type arguments, to refer to Pair[T, U]
+// within its own declaration.
+func makeTypeArgs(params *ast.FieldList) []ast.Expr {
+	args := h.EmptyE()
+	for _, field := range params.List {
+		for _, name := range field.Names {
+			args = append(args, ast.NewIdent(name.Name))
+		}
+	}
+	return args
+}
+
+// f[int, string]
+func makeInstantiation(x ast.Expr, typeArgs []ast.Expr) ast.Expr {
+	if len(typeArgs) == 0 {
+		return x
+	}
+
+	return &ast.IndexListExpr{X: x, Indices: typeArgs}
+}
+
+func isGenericDefn(node *parser.CallNode) bool {
+	return isCallTo(node, "defn") && len(node.Args) > 0 && node.Args[0].Type() == parser.NodeVector
+}
+
+// getDeclName returns the name a def, defn or defstruct declares.
+func getDeclName(node *parser.CallNode) *parser.IdentNode {
+	args := node.Args
+	if (isGenericDefn(node) || isCallTo(node, "defstruct")) && len(args) > 0 && args[0].Type() == parser.NodeVector {
+		args = args[1:]
+	}
+
+	if len(args) == 0 || !(isCallTo(node, "def") || isCallTo(node, "defn") || isCallTo(node, "defstruct")) {
+		return nil
+	}
+
+	name, _ := args[0].(*parser.IdentNode)
+	return name
+}
+
+// defnToDef rewrites (defn name [params] ...) as (def name (fn [params] ...)),
+// generic defns are generated by evalGenericDefn.
+func defnToDef(node *parser.CallNode) *parser.CallNode {
+	if len(node.Args) < 3 || node.Args[0].Type() != parser.NodeIdent || node.Args[1].Type() != parser.NodeVector {
+		panic("defn should look like: (defn name [params] body) or (defn [T constraint] name [params] body)")
+	}
+
+	fn := &parser.CallNode{NodeType: parser.NodeCall, Callee: parser.NewIdentNode("fn"), Args: node.Args[1:]}
+	return &parser.CallNode{NodeType: parser.NodeCall, Callee: parser.NewIdentNode("def"), Args: []parser.Node{node.Args[0], fn}}
+}
+
+// (defn [T comparable] index-of [xs ^[]T x ^T] ^int ...) is
+// func indexOf[T comparable](xs []T, x T) int { ... }
+func evalGenericDefn(node *parser.CallNode) ast.Decl {
+	if len(node.Args) < 4 || node.Args[1].Type() != parser.NodeIdent || node.Args[2].Type() != parser.NodeVector {
+		panic("defn should look like: (defn [T constraint] name [params] body)")
+	}
+
+	name := node.Args[1].(*parser.IdentNode).Ident
+	if name == "main" {
+		panic("main can't have type parameters!")
+	}
+
+	fnNode := &parser.CallNode{NodeType: parser.NodeCall, Callee: parser.NewIdentNode("fn"), Args: node.Args[2:]}
+	if !checkFuncArgs(fnNode) {
+		panic("invalid parameters of: " + name)
+	}
+
+	decl := makeFunDeclFromFuncLit(makeIdomaticIdent(name), makeFunc(fnNode, name))
+	decl.Type.TypeParams = makeTypeParams(node.Args[0].(*parser.VectorNode))
+
+	return decl
+}
@@ -0,0 +1,36 @@
+package generator
+
+import "testing"
+
+func TestDefn(t *testing.T) {
+	runCompileTests(t, []compileTest{
+		{"defn", `(defn add [a b] (+ a b))`, ""},
+		{"typed", `(defn index-of [xs ^[]int x ^int] ^int
+  (let [[i 0]]
+    (doseq [y xs] (when (= x y) (set! i 1)))
+    i))
+(defn f [] (+ 1 (index-of (vec int 1 2) 2)))`, ""},
+		{"typed rest", `(defn sum [& xs ^int] ^int (len xs))`, ""},
+		{"typed self call", `(defn count-down [n ^int] ^int (if (> n 0) (count-down (- n 1)) n))`, ""},
+		{"generic", `(defn [T comparable] index-of [xs ^[]T x ^T] ^int
+  (slices/index xs x))
+(defn f [] (index-of (vec string "a") "a"))`, ""},
+		{"instantiation", `(defn f [xs ^[]int] (slices/index ^[]int xs 1))`, ""},
+		{"bad defn", `(defn f)`, "defn should look like"},
+		{"hint first", `(defn f [^int x] x)`, "has to follow the name"},
+		{"invalid type", `(defn f [x ^int+] x)`, "invalid type"},
+		{"generic main", `(defn [T any] main [] nil)`, "main can't have type parameters"},
+		{"bad type params", `(defn [T] f [x ^T] x)`, "type parameters should look like"},
+	})
+}
+
+func TestDefstruct(t *testing.T) {
+	runCompileTests(t, []compileTest{
+		{"defstruct", `(defstruct Point [x ^int y ^int])
+(defn f [] (let [[p (->Point 1 2)]] (+ p/x p/y)))`, ""},
+		{"generic", `(defstruct [T any] Pair [first ^T second])
+(defn f [] (fmt/sprint (->Pair "a" 2)))`, ""},
+		{"bad defstruct", `(defstruct Point)`, "defstruct should look like"},
+		{"bad field", `(defstruct Point ["x"])`, "fields have to be identifiers"},
+	})
+}
@@ -0,0 +1,226 @@
+package generator
+
+import (
+	"github.com/jcla1/gisp/parser"
+	h "github.com/jcla1/gisp/generator/helpers"
+	"go/ast"
+	"go/token"
+	"strconv"
+)
+
+func isPattern(node parser.Node) bool {
+	switch node.Type() {
+	case parser.NodeVector, parser.NodeMap:
+		return true
+	}
+
+	return false
+}
+
+// isBindable reports whether node can be bound to, either
+// an identifier or a destructuring pattern.
+func isBindable(node parser.Node) bool {
+	return node.Type() == parser.NodeIdent || isPattern(node)
+}
+
+func coreCall(name string, args ...ast.Expr) *ast.CallExpr {
+	return makeFuncCall(makeSelectorExpr(ast.NewIdent("core"), ast.NewIdent(name)), args)
+}
+
+// makeDestructuring binds the identifiers in the pattern to the
+// parts of value, assignType being either := or =.
+//
+//	[a b & rest :as all] binds by position (core.Nth & core.NthRest)
+//	{:keys [a b] :strs [c] :or {a 1} :as m} binds by key (core.GetKey)
+//
+// A pattern needn't use all of its names, so they are all used once
+// by a _, _ = a, b after the bindings.
+func makeDestructuring(pattern parser.Node, value ast.Expr, assignType token.Token) []ast.Stmt {
+	stmts := destructure(pattern, value, assignType)
+	if !isPattern(pattern) || assignType != token.DEFINE {
+		return stmts
+	}
+
+	var blanks, names []ast.Expr
+	for _, name := range boundNames(pattern) {
+		if name != "_" {
+			blanks = append(blanks, ast.NewIdent("_"))
+			names = append(names, makeLocalIdent(name))
+		}
+	}
+
+	if len(names) == 0 {
+		return stmts
+	}
+
+	return append(stmts, makeAssignStmt(blanks, names, token.ASSIGN))
+}
+
+func destructure(pattern parser.Node, value ast.Expr, assignType token.Token) []ast.Stmt {
+	switch p := pattern.(type) {
+	case *parser.IdentNode:
+		if p.Ident == "_" {
+			assignType = token.ASSIGN
+		}
+
+		return h.S(makeAssignStmt(h.E(makeLocalIdent(p.Ident)), h.E(value), assignType))
+
+	case *parser.VectorNode:
+		tmp, stmts := makeDestructuringTemp(value)
+
+		for i, pos := 0, 0; i < len(p.Nodes); i++ {
+			switch elem := p.Nodes[i]; {
+			case isIdent(elem, "&"):
+				i++
+				checkPatternArg(p.Nodes, i, "&")
+				stmts = append(stmts, destructure(p.Nodes[i], coreCall("NthRest", tmp, makeIntLit(pos)), assignType)...)
+
+			case isKeywordNode(elem, "as"):
+				i++
+				checkPatternArg(p.Nodes, i, ":as")
+				stmts = append(stmts, destructure(p.Nodes[i], tmp, assignType)...)
+
+			case isBindable(elem):
+				stmts = append(stmts, destructure(elem, coreCall("Nth", tmp, makeIntLit(pos)), assignType)...)
+				pos++
+
+			default:
+				panic("can't destructure into: " + elem.String())
+			}
+		}
+
+		return stmts
+
+	case *parser.MapNode:
+		if len(p.Nodes)%2 != 0 {
+			panic("map destructuring needs :keys, :strs or :as followed by its bindings!")
+		}
+
+		tmp, stmts := makeDestructuringTemp(value)
+		defaults := getDestructuringDefaults(p)
+
+		for i := 0; i < len(p.Nodes); i += 2 {
+			switch {
+			case isKeywordNode(p.Nodes[i], "or"):
+				continue
+
+			case isKeywordNode(p.Nodes[i], "as"):
+				stmts = append(stmts, destructure(p.Nodes[i+1], tmp, assignType)...)
+
+			case isKeywordNode(p.Nodes[i], "keys"), isKeywordNode(p.Nodes[i], "strs"):
+				keys, ok := p.Nodes[i+1].(*parser.VectorNode)
+				if !ok {
+					panic(p.Nodes[i].String() + " needs a vector of identifiers!")
+				}
+
+				for _, key := range keys.Nodes {
+					ident, ok := key.(*parser.IdentNode)
+					if !ok {
+						panic(p.Nodes[i].String() + " needs a vector of identifiers!")
+					}
+
+					var k ast.Expr = makeBasicLit(token.STRING, strconv.Quote(ident.Ident))
+					if isKeywordNode(p.Nodes[i], "keys") {
+						k = coreCall("Keyword", k)
+					}
+
+					stmts = append(stmts, destructure(ident, coreCall("GetKey", tmp, k), assignType)...)
+
+					if def, ok := defaults[ident.Ident]; ok {
+						name := makeLocalIdent(ident.Ident)
+						isNil := makeBinaryExpr(token.EQL, name, ast.NewIdent("nil"))
+						setDefault := makeAssignStmt(h.E(name), h.E(EvalExpr(def)), token.ASSIGN)
+						stmts = append(stmts, makeIfStmt(isNil, makeBlockStmt(h.S(setDefault)), nil))
+					}
+				}
+
+			default:
+				panic("unknown map destructuring directive: " + p.Nodes[i].String())
+			}
+		}
+
+		return stmts
+	}
+
+	panic("can't destructure into: " + pattern.String())
+}
+
+// boundNames returns the names of all identifiers a binding, or
+// destructuring pattern, introduces.
+func boundNames(node parser.Node) []string {
+	names := []string{}
+
+	switch p := node.(type) {
+	case *parser.IdentNode:
+		if p.Ident != "&" {
+			names = append(names, p.Ident)
+		}
+
+	case *parser.VectorNode:
+		for _, elem := range p.Nodes {
+			names = append(names, boundNames(elem)...)
+		}
+
+	case *parser.MapNode:
+		for i := 0; i+1 < len(p.Nodes); i += 2 {
+			if !isKeywordNode(p.Nodes[i], "or") {
+				names = append(names, boundNames(p.Nodes[i+1])...)
+			}
+		}
+	}
+
+	return names
+}
+
+// The :or of a map destructuring maps identifiers to the values
+// they default to, in case their key is missing.
+func getDestructuringDefaults(pattern *parser.MapNode) map[string]parser.Node {
+	defaults := map[string]parser.Node{}
+
+	for i := 0; i < len(pattern.Nodes); i += 2 {
+		if !isKeywordNode(pattern.Nodes[i], "or") {
+			continue
+		}
+
+		or, ok := pattern.Nodes[i+1].(*parser.MapNode)
+		if !ok || len(or.Nodes)%2 != 0 {
+			panic(":or needs a map of identifiers to their defaults!")
+		}
+
+		for j := 0; j < len(or.Nodes); j += 2 {
+			ident, ok := or.Nodes[j].(*parser.IdentNode)
+			if !ok {
+				panic(":or needs a map of identifiers to their defaults!")
+			}
+			defaults[ident.Ident] = or.Nodes[j+1]
+		}
+	}
+
+	return defaults
+}
+
+// The value is only evaluated once, an identifier can be used as is
+func makeDestructuringTemp(value ast.Expr) (*ast.Ident, []ast.Stmt) {
+	if ident, ok := value.(*ast.Ident); ok {
+		return ident, h.EmptyS()
+	}
+
+	tmp := generateIdent()
+	return tmp, h.S(makeAssignStmt(h.E(tmp), h.E(value), token.DEFINE))
+}
+
+func isIdent(node parser.Node, name string) bool {
+	ident, ok := node.(*parser.IdentNode)
+	return ok && ident.Ident == name
+}
+
+func isKeywordNode(node parser.Node, name string) bool {
+	keyword, ok := node.(*parser.KeywordNode)
+	return ok && keyword.Name == name
+}
+
+func checkPatternArg(nodes []parser.Node, i int, directive string) {
+	if i >= len(nodes) || !isBindable(nodes[i]) {
+		panic(directive + " in a destructuring needs something to bind to!")
+	}
+}
@@ -0,0 +1,21 @@
+package generator
+
+import "testing"
+
+func TestDestructuring(t *testing.T) {
+	runCompileTests(t, []compileTest{
+		{"let vector", `(def f (fn [v] (let [[[a b] v]] (+ a b))))`, ""},
+		{"let rest", `(def f (fn [v] (let [[[a & more :as all] v]] (fmt/sprint a more all))))`, ""},
+		{"nested", `(def f (fn [v] (let [[[a [b c]] v]] (fmt/sprint a b c))))`, ""},
+		{"map keys", `(def f (fn [m] (let [[{:keys [a b] :strs [c] :as all} m]] (fmt/sprint a b c all))))`, ""},
+		{"fn params", `(def f (fn [[a b] {:keys [c]}] (fmt/sprint a b c)))`, ""},
+		{"call value", `(def f (fn [] (let [[[a b] (strings/fields "x y")]] (fmt/sprint a b))))`, ""},
+		{"unused names", `(def f (fn [v] (let [[[a b & more] v]] a)))`, ""},
+		{"unused keys", `(def f (fn [{:keys [a b] :as m}] b))`, ""},
+		{"unused param pattern", `(def f (fn [[a b] c] c))`, ""},
+		{"not bindable", `(def f (fn [v] (let [[[a "b"] v]] a)))`, "can't destructure into"},
+		{"unknown directive", `(def f (fn [m] (let [[{:vals [a]} m]] a)))`, "unknown map destructuring directive"},
+		{"keys needs vector", `(def f (fn [m] (let [[{:keys a} m]] a)))`, "needs a vector of identifiers"},
+		{"dangling as", `(def f (fn [v] (let [[[a :as] v]] a)))`, "needs something to bind to"},
+	})
+}
@@ -0,0 +1,144 @@
+package generator
+
+import (
+	"github.com/jcla1/gisp/parser"
+	h "github.com/jcla1/gisp/generator/helpers"
+	"go/ast"
+	"go/token"
+)
+
+var tryErrIdents = []string{"try-err", "<-"}
+
+func isTryErr(node *parser.CallNode) bool {
+	// Need an identifier for it to be "try-err"
+	if node.Callee.Type() != parser.NodeIdent {
+		return false
+	}
+
+	if !isInSlice(node.Callee.(*parser.IdentNode).Ident, tryErrIdents) {
+		return false
+	}
+
+	if len(node.Args) != 1 {
+		panic("try-err takes exactly one (value, error) returning call!")
+	}
+
+	return true
+}
+
+func makeTryErr(node *parser.CallNode) *ast.CallExpr {
+	return makeFuncCall(makeSelectorExpr(ast.NewIdent("core"), ast.NewIdent("TryErr")), EvalExprs(node.Args))
+}
+
+// searchForTryErr reports whether the nodes use try-err outside
+// of any nested fn, which will catch its own errors.
+func searchForTryErr(nodes []parser.Node) bool {
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *parser.CallNode:
+			if ident, ok := n.Callee.(*parser.IdentNode); ok {
+				if isInSlice(ident.Ident, tryErrIdents) {
+					return true
+				} else if ident.Ident == "fn" {
+					continue
+				}
+			}
+
+			if searchForTryErr(n.Args) {
+				return true
+			}
+		case *parser.VectorNode:
+			if searchForTryErr(n.Nodes) {
+				return true
+			}
+		case *parser.MapNode:
+			if searchForTryErr(n.Nodes) {
+				return true
+			}
+		case *parser.SetNode:
+			if searchForTryErr(n.Nodes) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// makeErrCatching names the result of the function and defers
+// core.CatchErr, so that a failing try-err returns its error.
+func makeErrCatching(fn *ast.FuncLit) {
+	result := generateIdent()
+	fn.Type.Results = makeFieldList([]*ast.Field{makeField(h.I(result), fn.Type.Results.List[0].Type)})
+
+	catch := makeFuncCall(makeSelectorExpr(ast.NewIdent("core"), ast.NewIdent("CatchErr")), h.E(makeUnaryExpr(token.AND, result)))
+	fn.Body.List = append(h.S(makeDeferStmt(catch)), fn.Body.List...)
+}
+
+func isIfOk(node *parser.CallNode) bool {
+	// Need an identifier for it to be "if-ok"
+	if node.Callee.Type() != parser.NodeIdent {
+		return false
+	}
+
+	if callee := node.Callee.(*parser.IdentNode); callee.Ident != "if-ok" {
+		return false
+	}
+
+	if len(node.Args) < 2 || len(node.Args) > 3 {
+		panic("if-ok needs a binding, a then and an optional else branch!")
+	}
+
+	binding, ok := node.Args[0].(*parser.VectorNode)
+	if !ok || len(binding.Nodes) < 2 || len(binding.Nodes) > 3 {
+		panic("if-ok binding should look like: [value call] or [value ok call]")
+	}
+
+	for _, ident := range binding.Nodes[:len(binding.Nodes)-1] {
+		if ident.Type() != parser.NodeIdent {
+			panic("if-ok can only bind identifiers!")
+		}
+	}
+
+	return true
+}
+
+// (if-ok [v (strconv/atoi s)] then else) checks the second return
+// value with core.Ok, it may either be a bool or an error.
+func makeIfOk(node *parser.CallNode) *ast.CallExpr {
+	binding := node.Args[0].(*parser.VectorNode).Nodes
+	value := makeLocalIdent(binding[0].(*parser.IdentNode).Ident)
+
+	okIdent := generateIdent()
+	if len(binding) == 3 {
+		okIdent = makeLocalIdent(binding[1].(*parser.IdentNode).Ident)
+	}
+
+	init := makeAssignStmt(h.E(value, okIdent), h.E(EvalExpr(binding[len(binding)-1])), token.DEFINE)
+
+	names := []string{}
+	for _, ident := range binding[:len(binding)-1] {
+		names = append(names, boundNames(ident)...)
+	}
+	pushLocals(names)
+	defer popLocals()
+	cond := makeFuncCall(makeSelectorExpr(ast.NewIdent("core"), ast.NewIdent("Ok")), h.E(okIdent))
+
+	var elseExpr ast.Expr = ast.NewIdent("nil")
+	if len(node.Args) > 2 {
+		elseExpr = EvalExpr(node.Args[2])
+	}
+
+	// the branches needn't use the value
+	use := makeAssignStmt(h.E(ast.NewIdent("_")), h.E(value), token.ASSIGN)
+	ifBody := makeBlockStmt(h.S(use, makeReturnStmt(h.E(EvalExpr(node.Args[1])))))
+	elseBody := makeBlockStmt(h.S(makeReturnStmt(h.E(elseExpr))))
+
+	ifStmt := makeIfStmt(cond, ifBody, elseBody)
+	ifStmt.Init = init
+
+	returnList := makeFieldList([]*ast.Field{makeField(nil, anyType)})
+	fn := makeFuncLit(makeFuncType(returnList, nil), makeBlockStmt(h.S(ifStmt)))
+
+	return makeFuncCall(fn, h.EmptyE())
+}
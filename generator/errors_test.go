@@ -0,0 +1,46 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTryErr(t *testing.T) {
+	runCompileTests(t, []compileTest{
+		{"try-err", `(def open (fn [] (let [[f (try-err (os/open "x"))]] f)))`, ""},
+		{"<-", `(def atoi (fn [s] (+ 1 (<- (strconv/atoi (assert string s))))))`, ""},
+		{"nested fn", `(def f (fn [] (fn [] (try-err (strconv/atoi "1")))))`, ""},
+		{"no call", `(def f (fn [] (try-err)))`, "exactly one"},
+	})
+}
+
+func TestTryErrInLiterals(t *testing.T) {
+	srcs := []string{
+		`(def f (fn [] [(try-err (strconv/atoi "1"))]))`,
+		`(def f (fn [] {:n (try-err (strconv/atoi "1"))}))`,
+		`(def f (fn [] #{(try-err (strconv/atoi "1"))}))`,
+	}
+
+	for _, src := range srcs {
+		goSrc, err := generate(src)
+		if err != nil {
+			t.Errorf("%s: %v", src, err)
+			continue
+		}
+		if !strings.Contains(goSrc, "core.CatchErr") {
+			t.Errorf("%s doesn't catch the error:\n%s", src, goSrc)
+		}
+	}
+}
+
+func TestIfOk(t *testing.T) {
+	runCompileTests(t, []compileTest{
+		{"error", `(def f (fn [] (if-ok [n (strconv/atoi "1")] n 0)))`, ""},
+		{"named ok", `(def f (fn [] (if-ok [n err (strconv/atoi "1")] n err)))`, ""},
+		{"no else", `(def f (fn [] (if-ok [n (strconv/atoi "1")] n)))`, ""},
+		{"unused value", `(def f (fn [] (if-ok [n (strconv/atoi "1")] "ok" "failed")))`, ""},
+		{"bool", `(def f (fn [] (if-ok [v (os/lookup-env "HOME")] v)))`, ""},
+		{"bad binding", `(def f (fn [] (if-ok [(strconv/atoi "1")] 1)))`, "binding should look like"},
+		{"non ident", `(def f (fn [] (if-ok ["n" (strconv/atoi "1")] 1)))`, "only bind identifiers"},
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/jcla1/gisp/parser"
 	"go/ast"
 	"go/token"
+	"strconv"
 )
 
 func EvalExprs(nodes []parser.Node) []ast.Expr {
@@ -24,18 +25,59 @@ func EvalExpr(node parser.Node) ast.Expr {
 
 	case parser.NodeVector:
 		node := node.(*parser.VectorNode)
-		return makeVector(anyType, EvalExprs(node.Nodes))
+		return makePersistentVector(EvalExprs(node.Nodes))
+
+	case parser.NodeMap:
+		node := node.(*parser.MapNode)
+		return makeMap(node.Nodes)
+
+	case parser.NodeSet:
+		node := node.(*parser.SetNode)
+		return makeSet(node.Nodes)
+
+	case parser.NodeRegex:
+		node := node.(*parser.RegexNode)
+		return makeRegex(node.Pattern)
+
+	case parser.NodeKeyword:
+		node := node.(*parser.KeywordNode)
+		return makeKeyword(node.Name)
 
 	case parser.NodeNumber:
 		node := node.(*parser.NumberNode)
+		if node.NumberType == token.INT && !fitsInt(node.Value) {
+			return makeBigNumber("BigInt", node.Value)
+		}
 		return makeBasicLit(node.NumberType, node.Value)
 
+	case parser.NodeBigInt:
+		node := node.(*parser.NumberNode)
+		return makeBigNumber("BigInt", node.Value)
+
+	case parser.NodeRatio:
+		node := node.(*parser.NumberNode)
+		return makeBigNumber("Ratio", node.Value)
+
+	case parser.NodeBool:
+		node := node.(*parser.BoolNode)
+		return ast.NewIdent(strconv.FormatBool(node.Value))
+
+	case parser.NodeNil:
+		return ast.NewIdent("nil")
+
 	case parser.NodeString:
 		node := node.(*parser.StringNode)
-		return makeBasicLit(token.STRING, node.Value)
+		return makeStringLit(node.Value)
+
+	case parser.NodeChar:
+		node := node.(*parser.CharNode)
+		return makeBasicLit(token.CHAR, strconv.QuoteRune(node.Value))
 
 	case parser.NodeIdent:
 		node := node.(*parser.IdentNode)
+		if name, ok := coreValue(node.Ident); ok {
+			return makeSelectorExpr(ast.NewIdent("core"), ast.NewIdent(name))
+		}
 		return makeIdomaticSelector(node.Ident)
 
 	default:
@@ -0,0 +1,53 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+var exportTests = []struct {
+	name      string
+	exportAll bool
+	src       string
+	want      []string
+}{
+	{"export", false, `(def ^:export sum-all (fn [x] x))
+(def helper (fn [] (sum-all 1)))`, []string{"func SumAll(", "func helper(", "return SumAll(1)"}},
+	{"export-all", true, `(def ^:private helper 1)
+(def answer 42)`, []string{"var helper = 1", "var Answer = 42"}},
+	{"main stays main", true, `(def main (fn [] nil))`, []string{"func main()"}},
+	{"shadowing param", false, `(def ^:export x 1)
+(def f (fn [x] (+ x 1)))`, []string{"var X = 1", "func f(x core.Any)", "core.ADD(x, 1)"}},
+	{"shadowing let", false, `(def ^:export n 1)
+(def f (fn [] (let [[n 2]] n)))
+(def g (fn [] n))`, []string{"n := 2", "return n\n", "return N\n"}},
+	{"let shadowing itself", false, `(def ^:export x 1)
+(def f (fn [] (let [[x (+ x 1)]] x)))`, []string{"x := core.ADD(X, 1)", "return x\n"}},
+	{"destructuring shadowing itself", false, `(def ^:export v [1])
+(def f (fn [] (let [[[v] v]] v)))`, []string{"v := core.Nth(V, 0)"}},
+	{"later bindings see earlier ones", false, `(def ^:export a 5)
+(def f (fn [] (let [[a 1] [b (+ a 1)]] b)))`, []string{"a := 1", "b := core.ADD(a, 1)"}},
+	{"loop shadowing itself", false, `(def ^:export n 1)
+(def f (fn [] (loop [[n (+ n 1)]] (if (< n 5) (recur (+ n 1)) n))))`, []string{"var n core.Any = core.ADD(N, 1)"}},
+	{"unexported field", false, `(def f (fn [x] (fmt/sprint x/.some-field x/other-field)))`, []string{"x.someField", "x.OtherField"}},
+}
+
+func TestExports(t *testing.T) {
+	defer func() { ExportAll = false }()
+
+	for _, test := range exportTests {
+		ExportAll = test.exportAll
+
+		goSrc, err := generate(test.src)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		for _, want := range test.want {
+			if !strings.Contains(goSrc, want) {
+				t.Errorf("%s: %q is missing in\n%s", test.name, want, goSrc)
+			}
+		}
+	}
+}
@@ -10,7 +10,7 @@ import (
 func evalFuncCall(node *parser.CallNode) ast.Expr {
 	switch {
 	case isUnaryOperator(node):
-		return makeUnaryExpr(unaryOperatorMap[node.Callee.(*parser.IdentNode).Ident], EvalExpr(node.Args[0]))
+		return makeUnaryOperatorExpr(node)
 
 	case isCallableOperator(node):
 		return makeNAryCallableExpr(node)
@@ -21,12 +21,39 @@ func evalFuncCall(node *parser.CallNode) ast.Expr {
 	case isLoop(node):
 		return makeLoop(node)
 
+	case isLoopStmt(node):
+		return makeLoopFunc(node)
+
+	case isBranch(node):
+		panic("break/continue have to be in statement position of a loop!")
+
 	case isRecur(node):
 		return makeRecur(node)
 
+	case isCallTo(node, "recur"):
+		panic("recur can only be used in tail position of a loop or fn!")
+
 	case isAssert(node):
 		return makeAssert(node)
 
+	case isNumericConversion(node):
+		return makeNumericConversion(node)
+
+	case isTryErr(node):
+		return makeTryErr(node)
+
+	case isIfOk(node):
+		return makeIfOk(node)
+
+	case checkNewArgs(node):
+		return makeNew(node)
+
+	case checkSetBangArgs(node):
+		return makeSetBang(node)
+
+	case checkSliceArgs(node):
+		return makeSliceForm(node)
+
 	case isCoreFunc(node):
 		return makeCoreCall(node)
 
@@ -36,28 +63,32 @@ func evalFuncCall(node *parser.CallNode) ast.Expr {
 	case checkIfArgs(node):
 		return makeIfStmtFunc(node)
 
-	case checkFuncArgs(node):
-		// TODO: In case of type annotations change the following
-		returnField := []*ast.Field{makeField(nil, anyType)}
-		results := makeFieldList(returnField)
+	case checkCondArgs(node):
+		return makeCondFunc(node)
 
-		argIdents, ellipsis := getArgIdentsFromVector(node.Args[0].(*parser.VectorNode))
-		params := make([]*ast.Field, 0, len(argIdents))
+	case checkCaseArgs(node):
+		return makeCaseFunc(node)
 
-		if len(argIdents) != 0 {
-			params = append(params, makeField(argIdents, anyType))
-		}
+	case checkTypeCaseArgs(node):
+		return makeTypeCaseFunc(node)
 
-		if ellipsis != nil {
-			params = append(params, makeField(h.I(ellipsis), makeEllipsis(anyType)))
-		}
+	case checkMatchArgs(node):
+		return makeMatchFunc(node)
+
+	case checkWhenArgs(node):
+		return makeWhenFunc(node)
+
+	case checkDoArgs(node):
+		return makeDoFunc(node)
 
-		fnType := makeFuncType(results, makeFieldList(params))
-		body := makeFuncBody(EvalExprs(node.Args[1:]))
+	case checkFuncArgs(node):
+		return makeFunc(node, "")
 
-		return makeFuncLit(fnType, body)
+	case checkMultiArityFuncArgs(node):
+		return makeMultiArityFunc(node, "")
 
-	case checkDefArgs(node):
+	case checkDefArgs(node), isCallTo(node, "defn"), isCallTo(node, "defstruct"), isCallTo(node, "defunion"),
+		isCallTo(node, "defprotocol"), isCallTo(node, "extend-type"), isCallTo(node, "defmulti"), isCallTo(node, "defmethod"):
 		panic("you can't have a def within an expression!")
 
 	case checkNSArgs(node):
@@ -65,34 +96,109 @@ func evalFuncCall(node *parser.CallNode) ast.Expr {
 	}
 
 	callee := EvalExpr(node.Callee)
-	if c, ok := callee.(*ast.Ident); ok {
-		callee = makeIdomaticIdent(c.Name)
+
+	// (slices/index ^[]int xs 1) instantiates slices.Index[[]int]
+	args := node.Args
+	typeArgs := h.EmptyE()
+	for len(args) > 0 && args[0].Type() == parser.NodeTypeHint {
+		typeArgs = append(typeArgs, evalType(args[0]))
+		args = args[1:]
+	}
+
+	return makeFuncCall(makeInstantiation(callee, typeArgs), EvalExprs(args))
+}
+
+// makeFunc generates a fn, self being the name it was def'd
+// with, so that it can call itself in tail position.
+func makeFunc(node *parser.CallNode, self string) *ast.FuncLit {
+	node, paramTypes, resultType := stripFuncTypes(node)
+
+	var returnField []*ast.Field
+	if resultType != nil {
+		returnField = []*ast.Field{makeField(nil, resultType)}
+	} else {
+		returnField = []*ast.Field{makeField(nil, anyType)}
 	}
+	results := makeFieldList(returnField)
 
-	args := EvalExprs(node.Args)
+	argIdents, ellipsis, destructuring := getArgIdentsFromVector(node.Args[0].(*parser.VectorNode))
+	pushLocals(boundNames(node.Args[0]))
+	defer popLocals()
+	params := makeParamFields(argIdents, paramTypes)
+
+	if ellipsis != nil {
+		restType := paramTypes[len(argIdents)]
+		if restType == nil {
+			restType = anyType
+		}
+		params = append(params, makeField(h.I(ellipsis), makeEllipsis(restType)))
+	}
+
+	// break and continue can't cross the function boundary
+	outerLoops := loopLabels
+	loopLabels = nil
+	defer func() { loopLabels = outerLoops }()
+
+	fnType := makeFuncType(results, makeFieldList(params))
+
+	var body *ast.BlockStmt
+	target := &selfTarget{self: self, params: argIdents, ellipsis: ellipsis, types: paramTypes}
+	if hasTailCall(node.Args[1:], target) {
+		body = makeSelfTailLoop(destructuring, node.Args[1:], target)
+	} else {
+		body = makeFuncBody(EvalExprs(node.Args[1:]))
+		body.List = append(destructuring, body.List...)
+	}
 
-	return makeFuncCall(callee, args)
+	fn := makeFuncLit(fnType, body)
+
+	if resultType != nil {
+		convertReturns(fn.Body, resultType)
+	}
+
+	if searchForTryErr(node.Args[1:]) {
+		makeErrCatching(fn)
+	}
+
+	return fn
 }
 
-func getArgIdentsFromVector(vect *parser.VectorNode) ([]*ast.Ident, *ast.Ident) {
+// getArgIdentsFromVector returns the parameters, the rest parameter
+// after "&" and the statements destructuring any patterns, which
+// were given generated names.
+func getArgIdentsFromVector(vect *parser.VectorNode) ([]*ast.Ident, *ast.Ident, []ast.Stmt) {
 	args := vect.Nodes
+	checkNameCollisions(boundNames(vect))
+
 	argIdents := make([]*ast.Ident, 0, len(vect.Nodes))
+	destructuring := h.EmptyS()
 
-	var ident string
 	var ellipsis *ast.Ident
 
+	param := func(node parser.Node) *ast.Ident {
+		if !isPattern(node) {
+			return makeLocalIdent(node.(*parser.IdentNode).Ident)
+		}
+
+		ident := generateIdent()
+		destructuring = append(destructuring, makeDestructuring(node, ident, token.DEFINE)...)
+		return ident
+	}
+
 	for i := 0; i < len(args); i++ {
-		ident = args[i].(*parser.IdentNode).Ident
+		if ident, ok := args[i].(*parser.IdentNode); ok && ident.Ident == "&" {
+			if i+1 >= len(args) {
+				panic("missing rest parameter after &")
+			}
 
-		if ident == "&" {
-			ellipsis = makeIdomaticIdent(args[i+1].(*parser.IdentNode).Ident)
+			ellipsis = param(args[i+1])
 			break
 		}
 
-		argIdents = append(argIdents, makeIdomaticIdent(ident))
+		argIdents = append(argIdents, param(args[i]))
 	}
 
-	return argIdents, ellipsis
+	return argIdents, ellipsis, destructuring
 }
 
 func makeFuncBody(exprs []ast.Expr) *ast.BlockStmt {
@@ -184,7 +290,7 @@ func checkFuncArgs(node *parser.CallNode) bool {
 	}
 
 	// Need argument list and at least one expression
-	if len(node.Args) < 2 {
+	if len(node.Args) < 2 || (node.Args[1].Type() == parser.NodeTypeHint && len(node.Args) < 3) {
 		return false
 	}
 
@@ -196,8 +302,7 @@ func checkFuncArgs(node *parser.CallNode) bool {
 
 	p := params.(*parser.VectorNode)
 	for _, param := range p.Nodes {
-		// TODO: change this in case of variable unpacking
-		if param.Type() != parser.NodeIdent {
+		if !isBindable(param) && param.Type() != parser.NodeTypeHint {
 			return false
 		}
 	}
@@ -235,10 +340,10 @@ func checkLetArgs(node *parser.CallNode) bool {
 		}
 	}
 
-	// The bound identifiers, should be identifiers
+	// The bound identifiers, should be identifiers or patterns
 	for _, bind := range b.Nodes {
 		bindingVect := bind.(*parser.VectorNode)
-		if bindingVect.Nodes[0].Type() != parser.NodeIdent {
+		if len(bindingVect.Nodes) < 2 || !isBindable(bindingVect.Nodes[0]) {
 			return false
 		}
 	}
@@ -271,10 +376,10 @@ func isLoop(node *parser.CallNode) bool {
 		}
 	}
 
-	// The bound identifiers, should be identifiers
+	// The bound identifiers, should be identifiers or patterns
 	for _, bind := range b.Nodes {
 		bindingVect := bind.(*parser.VectorNode)
-		if bindingVect.Nodes[0].Type() != parser.NodeIdent {
+		if len(bindingVect.Nodes) < 2 || !isBindable(bindingVect.Nodes[0]) {
 			return false
 		}
 	}
@@ -297,6 +402,11 @@ func isRecur(node *parser.CallNode) bool {
 		return false
 	}
 
+	// Not yet rewritten by an enclosing loop
+	if len(node.Args) != 2 {
+		return false
+	}
+
 	// Bindings should be a vector
 	bindings := node.Args[0]
 	if bindings.Type() != parser.NodeVector {
@@ -311,10 +421,10 @@ func isRecur(node *parser.CallNode) bool {
 		}
 	}
 
-	// The bound identifiers, should be identifiers
+	// The bound identifiers, should be identifiers or patterns
 	for _, bind := range b.Nodes {
 		bindingVect := bind.(*parser.VectorNode)
-		if bindingVect.Nodes[0].Type() != parser.NodeIdent {
+		if len(bindingVect.Nodes) < 2 || !isBindable(bindingVect.Nodes[0]) {
 			return false
 		}
 	}
@@ -374,7 +484,10 @@ func makeLoop(node *parser.CallNode) *ast.CallExpr {
 
 	addRecurLabelAndBindings(parser.NewIdentNode(loopIdent.String()), bindingsVector.Copy().(*parser.VectorNode), node.Args[1:])
 
-	bindings := makeBindings(bindingsVector, token.DEFINE)
+	// recur may give the loop's names any kind of value
+	bindings := declareAsAny(makeBindings(bindingsVector, token.DEFINE), func(string) bool { return true })
+	defer popLocals()
+
 	returnIdentValueSpec := makeValueSpec(h.I(returnIdent), nil, anyType)
 	returnIdentDecl := makeDeclStmt(makeGeneralDecl(token.VAR, []ast.Spec{returnIdentValueSpec}))
 
@@ -419,27 +532,63 @@ func isAssert(node *parser.CallNode) bool {
 		return false
 	}
 
-	// Not a "loop"
-	if callee := node.Callee.(*parser.IdentNode); callee.Ident != "assert" {
+	// Not an "assert" or "assert-ok"
+	callee := node.Callee.(*parser.IdentNode).Ident
+	if callee != "assert" && callee != "assert-ok" {
 		return false
 	}
 
 	if len(node.Args) != 2 {
-		panic("assert needs 2 arguments")
+		panic(callee + " needs 2 arguments")
 	}
 
-	if _, ok := node.Args[0].(*parser.IdentNode); !ok {
-		panic("assert's first argument needs to be a type")
+	if t := node.Args[0].Type(); t != parser.NodeIdent && t != parser.NodeCall {
+		panic(callee + "'s first argument needs to be a type")
 	}
 
 	return true
 }
 
-func makeAssert(node *parser.CallNode) *ast.TypeAssertExpr {
-	return makeTypeAssertion(EvalExpr(node.Args[1]), ast.NewIdent(node.Args[0].(*parser.IdentNode).Ident))
+func makeAssert(node *parser.CallNode) ast.Expr {
+	if isCallTo(node, "assert-ok") {
+		return makeAssertOk(node)
+	}
+
+	return makeTypeAssertion(EvalExpr(node.Args[1]), evalType(node.Args[0]))
 }
 
-var coreFuncs = []string{"get"}
+// coreFuncs maps the gisp functions living in core to their Go
+// names, which don't follow the mangling of gisp names.
+var coreFuncs = map[string]string{
+	"get":         "Get",
+	"count":       "Count",
+	"apply":       "Apply",
+	"trampoline":  "Trampoline",
+	"comp":        "Comp",
+	"partial":     "Partial",
+	"juxt":        "Juxt",
+	"complement":  "Complement",
+	"nil?":        "IsNil",
+	"quot":        "Quot",
+	"rem":         "Rem",
+	"compare":     "Compare",
+	"vector":      "Vector",
+	"hash-map":    "HashMap",
+	"sorted-map":  "SortedMap",
+	"hash-set":    "HashSet",
+	"sorted-set":  "SortedSet",
+	"conj":        "Conj",
+	"assoc":       "Assoc",
+	"dissoc":      "Dissoc",
+	"disj":        "Disj",
+	"contains?":   "IsContains",
+	"transient":   "Transient",
+	"persistent!": "PersistentBang",
+	"conj!":       "ConjBang",
+	"assoc!":      "AssocBang",
+	"dissoc!":     "DissocBang",
+	"disj!":       "DisjBang",
+}
 
 func isCoreFunc(node *parser.CallNode) bool {
 	// Need an identifier for it to be a func
@@ -447,20 +596,29 @@ func isCoreFunc(node *parser.CallNode) bool {
 		return false
 	}
 
-	ident := node.Callee.(*parser.IdentNode).Ident
+	_, ok := coreFuncs[node.Callee.(*parser.IdentNode).Ident]
+	return ok
+}
 
-	for _, v := range coreFuncs {
-		if v == ident {
-			return true
-		}
+// coreValue returns the Go name in core of a core function or an
+// operator passed as a value, as in ((partial + 10) 5), unless a
+// local or a def shadows it.
+func coreValue(name string) (string, bool) {
+	if _, isDef := packageDefs[name]; isDef || isLocal(name) {
+		return "", false
 	}
 
-	return false
+	if goName, ok := coreFuncs[name]; ok {
+		return goName, true
+	}
+
+	goName, ok := callableOperators[name]
+	return goName, ok
 }
 
 // TODO: just a quick and dirty implementation
 func makeCoreCall(node *parser.CallNode) ast.Expr {
 	ident := node.Callee.(*parser.IdentNode).Ident
-	node.Callee.(*parser.IdentNode).Ident = "core/" + ident
+	node.Callee.(*parser.IdentNode).Ident = "core/" + coreFuncs[ident]
 	return evalFuncCall(node)
 }
\ No newline at end of file
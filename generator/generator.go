@@ -9,6 +9,17 @@ import (
 
 var anyType = makeSelectorExpr(ast.NewIdent("core"), ast.NewIdent("Any"))
 
+// Declarations generated along the way, that have to be at the top
+// of the package, i.e. the compiled regex literals.
+var packageDecls []ast.Decl
+
+// ExportAll exports every def that isn't tagged ^:private,
+// otherwise only the ones tagged ^:export are.
+var ExportAll = false
+
+// The def'd names that are exported, i.e. capitalized in Go.
+var exportedNames map[string]bool
+
 func GenerateAST(tree []parser.Node) *ast.File {
 	f := &ast.File{Name: ast.NewIdent("main")}
 	decls := make([]ast.Decl, 0, len(tree))
@@ -17,38 +28,164 @@ func GenerateAST(tree []parser.Node) *ast.File {
 		return f
 	}
 
+	packageDecls = nil
+	regexIdents = map[string]*ast.Ident{}
+	exportedNames = map[string]bool{}
+	packageDefs = map[string]bool{}
+	localScopes = nil
+	unions = map[string]*union{}
+	variants = map[string]*variant{}
+	protocols = map[string]*protocol{}
+
+	var imports ast.Decl
+
 	// you can only have (ns ...) as the first form
 	if isNSDecl(tree[0]) {
-		name, imports := getNamespace(tree[0].(*parser.CallNode))
+		var name *ast.Ident
+		name, imports = getNamespace(tree[0].(*parser.CallNode))
 
 		f.Name = name
-		if imports != nil {
-			decls = append(decls, imports)
-		}
-
 		tree = tree[1:]
 	}
 
-	decls = append(decls, generateDecls(tree)...)
+	generated := generateDecls(tree)
+
+	if len(regexIdents) > 0 {
+		imports = addImport(imports, "regexp")
+	}
+
+	if imports != nil {
+		decls = append(decls, imports)
+	}
+
+	decls = append(decls, packageDecls...)
+	decls = append(decls, generated...)
 
 	f.Decls = decls
 	return f
 }
 
 func generateDecls(tree []parser.Node) []ast.Decl {
-	decls := make([]ast.Decl, len(tree))
+	decls := make([]ast.Decl, 0, len(tree))
+
+	tree = ExpandThreading(tree)
+	names := []string{}
+	declare := func(ident *parser.IdentNode, declared []string, isFn bool) {
+		for _, name := range declared {
+			exportedNames[name] = isExported(ident)
+			packageDefs[name] = isFn
+		}
+		names = append(names, declared...)
+	}
 
 	for i, node := range tree {
+		call, ok := node.(*parser.CallNode)
+		if !ok {
+			continue
+		}
+
+		if isCallTo(call, "defn") && !isGenericDefn(call) {
+			call = defnToDef(call)
+			tree[i] = call
+		}
+
+		switch {
+		case isCallTo(call, "defunion"):
+			declare(call.Args[0].(*parser.IdentNode), registerUnion(call), true)
+			continue
+
+		case isCallTo(call, "defprotocol"):
+			declare(call.Args[0].(*parser.IdentNode), registerProtocol(call), true)
+			continue
+
+		case isCallTo(call, "defmulti"):
+			checkDefmultiArgs(call)
+			ident := call.Args[0].(*parser.IdentNode)
+			declare(ident, []string{ident.Ident, ident.Ident + "-methods"}, true)
+			continue
+		}
+
+		ident := getDeclName(call)
+		if ident == nil {
+			continue
+		}
+
+		declared := []string{ident.Ident}
+		if isCallTo(call, "defstruct") {
+			declared = append(declared, "->"+ident.Ident)
+		}
+		declare(ident, declared, isFnDef(call))
+	}
+	names = append(names, registerAdapters(tree)...)
+	checkNameCollisions(names)
+
+	groups := map[string]*tailGroup{}
+	for _, group := range findTailGroups(tree) {
+		for _, name := range group.names {
+			groups[name] = group
+		}
+	}
+
+	for _, node := range tree {
 		if node.Type() != parser.NodeCall {
 			panic("expected call node in root scope!")
 		}
 
-		decls[i] = evalDeclNode(node.(*parser.CallNode))
+		name, _ := getDefdFunc(node)
+		if group, ok := groups[name]; ok {
+			if name == group.names[0] {
+				decls = append(decls, makeDispatchDecl(group))
+			}
+
+			decls = append(decls, makeGroupMemberDecl(group, name))
+			continue
+		}
+
+		switch call := node.(*parser.CallNode); {
+		case isCallTo(call, "defstruct"):
+			decls = append(decls, evalDefstruct(call)...)
+		case isCallTo(call, "defunion"):
+			decls = append(decls, evalDefunion(call)...)
+		case isCallTo(call, "defprotocol"):
+			decls = append(decls, evalDefprotocol(call)...)
+		case isCallTo(call, "extend-type"):
+			decls = append(decls, evalExtendType(call)...)
+		case isCallTo(call, "defmulti"):
+			decls = append(decls, evalDefmulti(call)...)
+		case isCallTo(call, "defmethod"):
+			decls = append(decls, evalDefmethod(call))
+		default:
+			decls = append(decls, evalDeclNode(call))
+		}
 	}
 
 	return decls
 }
 
+// isFnDef reports whether the def declares a fn (or a type),
+// which can't be assigned to.
+func isFnDef(def *parser.CallNode) bool {
+	if isCallTo(def, "defn") || isCallTo(def, "defstruct") {
+		return true
+	}
+
+	fn, ok := def.Args[len(def.Args)-1].(*parser.CallNode)
+	return ok && isCallTo(fn, "fn")
+}
+
+func isExported(ident *parser.IdentNode) bool {
+	switch {
+	case ident.Ident == "main" || ident.Ident == "init":
+		return false
+	case ident.HasMeta("export"):
+		return true
+	case ident.HasMeta("private"):
+		return false
+	}
+
+	return ExportAll
+}
+
 func evalDeclNode(node *parser.CallNode) ast.Decl {
 	// Let's just assume that all top-level functions called will be "def"
 	if node.Callee.Type() != parser.NodeIdent {
@@ -59,6 +196,8 @@ func evalDeclNode(node *parser.CallNode) ast.Decl {
 	switch callee.Ident {
 	case "def":
 		return evalDef(node)
+	case "defn":
+		return evalGenericDefn(node)
 	}
 
 	return nil
@@ -69,10 +208,19 @@ func evalDef(node *parser.CallNode) ast.Decl {
 		panic(fmt.Sprintf("expecting expression to be assigned to variable: %q", node.Args[0]))
 	}
 
-	val := EvalExpr(node.Args[1])
+	name := node.Args[0].(*parser.IdentNode).Ident
+
+	var val ast.Expr
+	if fnNode, ok := node.Args[1].(*parser.CallNode); ok && checkFuncArgs(fnNode) {
+		val = makeFunc(fnNode, name)
+	} else if ok && checkMultiArityFuncArgs(fnNode) {
+		val = makeMultiArityFunc(fnNode, name)
+	} else {
+		val = EvalExpr(node.Args[1])
+	}
 	fn, ok := val.(*ast.FuncLit)
 
-	ident := makeIdomaticIdent(node.Args[0].(*parser.IdentNode).Ident)
+	ident := makeIdomaticIdent(name)
 
 	if ok {
 		if ident.Name == "main" {
@@ -0,0 +1,92 @@
+package generator
+
+import (
+	"github.com/jcla1/gisp/parser"
+	"bytes"
+	"fmt"
+	"go/ast"
+	goparser "go/parser"
+	"go/importer"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+)
+
+// The packages a test program may use, the unused ones are ignored.
+const testImports = `(ns main "fmt" "errors" "os" "slices" "strconv" "strings" "github.com/jcla1/gisp/core")`
+
+// Shared by all the tests, so that core is only type checked once.
+var (
+	fset     = token.NewFileSet()
+	imported = importer.ForCompiler(fset, "source", nil)
+)
+
+// generate compiles the gisp forms into Go source, returning the
+// panic of the generator if it fails.
+func generate(src string) (goSrc string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	tree := parser.ParseFromString("test.gsp", testImports+"\n"+src+"\n")
+
+	var buf bytes.Buffer
+	printer.Fprint(&buf, token.NewFileSet(), GenerateAST(tree))
+	return buf.String(), nil
+}
+
+// typeCheck reports the first error of the Go source, other than
+// the imports a test didn't need.
+func typeCheck(goSrc string) error {
+	file, err := goparser.ParseFile(fset, "test.go", goSrc, 0)
+	if err != nil {
+		return err
+	}
+
+	var first error
+	conf := types.Config{
+		Importer: imported,
+		Error: func(err error) {
+			if first == nil && !strings.Contains(err.Error(), "imported and not used") {
+				first = err
+			}
+		},
+	}
+	conf.Check("main", fset, []*ast.File{file}, nil)
+
+	return first
+}
+
+// A compileTest is a gisp program that has to generate Go which
+// type checks, or if fails is set, make the generator panic with a
+// message containing it.
+type compileTest struct {
+	name  string
+	src   string
+	fails string
+}
+
+func runCompileTests(t *testing.T, tests []compileTest) {
+	t.Helper()
+
+	for _, test := range tests {
+		goSrc, err := generate(test.src)
+
+		switch {
+		case test.fails != "" && err == nil:
+			t.Errorf("%s: generated, want a panic with %q", test.name, test.fails)
+		case test.fails != "" && !strings.Contains(err.Error(), test.fails):
+			t.Errorf("%s: panicked with %q, want %q", test.name, err, test.fails)
+		case test.fails == "" && err != nil:
+			t.Errorf("%s: %v", test.name, err)
+		case test.fails == "":
+			if err := typeCheck(goSrc); err != nil {
+				t.Errorf("%s: %v\n%s", test.name, err, goSrc)
+			}
+		}
+	}
+}
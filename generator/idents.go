@@ -3,10 +3,13 @@ package generator
 import (
 	"github.com/jcla1/gisp/parser"
 	"bytes"
+	"fmt"
 	"go/ast"
 	"regexp"
 	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 func makeIdentSlice(nodes []*parser.IdentNode) []*ast.Ident {
@@ -29,16 +32,43 @@ func makeIdomaticSelector(src string) ast.Expr {
 	var expr ast.Expr = makeIdomaticIdent(strs[0])
 
 	for i := 1; i < len(strs); i++ {
-		ido := CamelCase(strs[i], true)
+		// x/.field leaves the field unexported
+		var ido string
+		if strings.HasPrefix(strs[i], ".") {
+			ido = Mangle(strs[i][1:], false)
+		} else {
+			ido = Mangle(strs[i], true)
+		}
 		expr = makeSelectorExpr(expr, ast.NewIdent(ido))
 	}
 
 	return expr
 }
 
+// makeIdomaticIdent names a reference to src, or the declaration of
+// a def, which is exported if its def is and no local shadows it.
 func makeIdomaticIdent(src string) *ast.Ident {
 	if src == "_" { return ast.NewIdent(src) }
-	return ast.NewIdent(CamelCase(src, false))
+	if strings.HasPrefix(src, "%") { return makeAnonFnArg(src) }
+	return ast.NewIdent(Mangle(src, exportedNames[src] && !isLocal(src)))
+}
+
+// makeLocalIdent names a local that src binds, never exported.
+func makeLocalIdent(src string) *ast.Ident {
+	if src == "_" || strings.HasPrefix(src, "%") {
+		return makeIdomaticIdent(src)
+	}
+
+	return ast.NewIdent(Mangle(src, false))
+}
+
+// The arguments of #(...), %1 becomes arg1 and %& restArgs
+func makeAnonFnArg(src string) *ast.Ident {
+	if src == "%&" {
+		return ast.NewIdent("restArgs")
+	}
+
+	return ast.NewIdent("arg" + src[1:])
 }
 
 var camelingRegex = regexp.MustCompile("[0-9A-Za-z]+")
@@ -54,6 +84,107 @@ func CamelCase(src string, capit bool) string {
 	return string(bytes.Join(chunks, nil))
 }
 
+// The words punctuation in a gisp name is spelled as in Go,
+// "-" and "_" only separate words.
+var punctuationWords = map[rune]string{
+	'?':  "p",
+	'!':  "b",
+	'*':  "star",
+	'\'': "prime",
+	'%':  "percent",
+	'+':  "plus",
+	'<':  "lt",
+	'>':  "gt",
+	'=':  "eq",
+	'&':  "and",
+}
+
+// Mangle turns a gisp name into a Go identifier, camel casing it
+// and spelling out its punctuation. The punctuation is set apart by
+// underscores, which plain names never turn into, so even? and
+// is-even can't end up as the same identifier:
+//
+//	sum-even-fib  sumEvenFib
+//	even?         even_P
+//	reset!        reset_B
+//	map->vec      map_To_Vec
+//	*debug*       star_Debug_Star
+//	x'            x_Prime
+func Mangle(src string, capit bool) string {
+	type word struct {
+		text  string
+		punct bool
+	}
+	words := []word{}
+
+	chunk := []rune{}
+	split := func() {
+		if len(chunk) > 0 {
+			words = append(words, word{string(chunk), false})
+			chunk = chunk[:0]
+		}
+	}
+
+	runes := []rune(src)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			chunk = append(chunk, r)
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '>':
+			split()
+			words = append(words, word{"to", true})
+			i++
+		case r == '-' || r == '_':
+			split()
+		default:
+			split()
+			if w, ok := punctuationWords[r]; ok {
+				words = append(words, word{w, true})
+			}
+		}
+	}
+	split()
+
+	if len(words) == 0 {
+		panic(fmt.Sprintf("%q can't be turned into a Go identifier!", src))
+	}
+
+	var b strings.Builder
+	for idx, w := range words {
+		if idx > 0 && (w.punct || words[idx-1].punct) {
+			b.WriteByte('_')
+		}
+		if idx > 0 || capit {
+			w.text = upperFirst(w.text)
+		}
+		b.WriteString(w.text)
+	}
+	return b.String()
+}
+
+func upperFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+// checkNameCollisions panics if two of the names, bound in the
+// same scope, would end up as the same Go identifier.
+func checkNameCollisions(names []string) {
+	seen := map[string]string{}
+	for _, name := range names {
+		ident := makeLocalIdent(name).Name
+		if ident == "_" {
+			continue
+		}
+
+		if other, ok := seen[ident]; ok && other != name {
+			panic(fmt.Sprintf("%s and %s would both be called %s in Go!", other, name, ident))
+		}
+		seen[ident] = name
+	}
+}
+
 var gensyms = func() <-chan string {
 	syms := make(chan string)
 	go func() {
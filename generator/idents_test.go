@@ -0,0 +1,54 @@
+package generator
+
+import "testing"
+
+var mangleTests = []struct {
+	src     string
+	capit   bool
+	mangled string
+}{
+	{"x", false, "x"},
+	{"sum-even-fib", false, "sumEvenFib"},
+	{"sum-even-fib", true, "SumEvenFib"},
+	{"even?", false, "even_P"},
+	{"even?", true, "Even_P"},
+	{"reset!", false, "reset_B"},
+	{"map->vec", false, "map_To_Vec"},
+	{"*debug*", false, "star_Debug_Star"},
+	{"x'", false, "x_Prime"},
+	{"a<=b", false, "a_Lt_Eq_B"},
+	{"snake_case", false, "snakeCase"},
+	{"λ-fn", false, "λFn"},
+	{"is-even", false, "isEven"},
+}
+
+func TestMangle(t *testing.T) {
+	for _, test := range mangleTests {
+		if got := Mangle(test.src, test.capit); got != test.mangled {
+			t.Errorf("Mangle(%q, %v) = %q, want %q", test.src, test.capit, got, test.mangled)
+		}
+	}
+}
+
+func TestNameCollisions(t *testing.T) {
+	runCompileTests(t, []compileTest{
+		{"punctuation", `(def even? (fn [n] (= n 0)))
+(def reset! (fn [x'] x'))
+(def f (fn [] (reset! (even? 2))))`, ""},
+		{"top-level", `(def foo-bar 1)
+(def fooBar 2)`, "foo-bar and fooBar would both be called fooBar in Go!"},
+		{"params", `(def f (fn [a-b aB] a-b))`, "a-b and aB would both be called aB in Go!"},
+		{"let", `(def f (fn [] (let [[x-y 1] [xY 2]] xY)))`, "x-y and xY would both be called xY in Go!"},
+		{"predicate and is", `(def f (fn [] (let [[is-x 1] [x? 2]] (fmt/sprint is-x x?))))`, ""},
+		{"no name", `(def f (fn [] (let [[__ 1]] __)))`, "can't be turned into a Go identifier"},
+		{"destructuring", `(def f (fn [[a-b {:keys [aB]}]] aB))`, "would both be called aB"},
+	})
+}
+
+func TestCoreFuncNames(t *testing.T) {
+	runCompileTests(t, []compileTest{
+		{"predicates", `(def f (fn [m] (fmt/sprint (nil? m) (contains? m :a))))`, ""},
+		{"transients", `(def f (fn [v] (persistent! (conj! (transient v) 1))))`, ""},
+		{"shadowing a core name", `(def nil? (fn [x] 1))`, ""},
+	})
+}
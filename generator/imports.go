@@ -4,6 +4,7 @@ import (
 	"github.com/jcla1/gisp/parser"
 	"go/ast"
 	"go/token"
+	"strconv"
 )
 
 func getImports(node *parser.CallNode) ast.Decl {
@@ -30,6 +31,28 @@ func getImports(node *parser.CallNode) ast.Decl {
 	return decl
 }
 
+// addImport makes sure the path is imported (without a name),
+// creating the import declaration if there is none yet.
+func addImport(imports ast.Decl, path string) ast.Decl {
+	quoted := strconv.Quote(path)
+
+	if imports == nil {
+		decl := makeGeneralDecl(token.IMPORT, []ast.Spec{})
+		decl.Lparen = token.Pos(1)
+		imports = decl
+	}
+
+	decl := imports.(*ast.GenDecl)
+	for _, spec := range decl.Specs {
+		if imp := spec.(*ast.ImportSpec); imp.Path.Value == quoted && imp.Name == nil {
+			return decl
+		}
+	}
+
+	decl.Specs = append(decl.Specs, makeImportSpec(makeBasicLit(token.STRING, quoted), nil))
+	return decl
+}
+
 func makeImportSpecFromVector(vect *parser.VectorNode) *ast.ImportSpec {
 	if len(vect.Nodes) < 3 {
 		panic("invalid use of import!")
@@ -42,7 +65,7 @@ func makeImportSpecFromVector(vect *parser.VectorNode) *ast.ImportSpec {
 	pathString := vect.Nodes[0].(*parser.StringNode).Value
 	path := makeBasicLit(token.STRING, pathString)
 
-	if vect.Nodes[1].Type() != parser.NodeIdent || vect.Nodes[1].(*parser.IdentNode).Ident != ":as" {
+	if !isKeywordNode(vect.Nodes[1], "as") {
 		panic("invalid use of import! expecting: \":as\"!!!")
 	}
 	name := ast.NewIdent(vect.Nodes[2].(*parser.IdentNode).Ident)
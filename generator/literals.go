@@ -1,8 +1,14 @@
 package generator
 
 import (
+	"github.com/jcla1/gisp/parser"
+	"errors"
+	"fmt"
 	"go/ast"
 	"go/token"
+	"regexp"
+	"strconv"
+	"strings"
 )
 
 func makeBasicLit(kind token.Token, value string) *ast.BasicLit {
@@ -13,9 +19,134 @@ func makeVector(typ ast.Expr, elements []ast.Expr) *ast.CompositeLit {
 	return makeCompositeLit(&ast.ArrayType{Elt: typ}, elements)
 }
 
+// [1 2 3] becomes a core.Vector(1, 2, 3), a persistent vector
+func makePersistentVector(elements []ast.Expr) *ast.CallExpr {
+	return coreCall("Vector", elements...)
+}
+
 func makeCompositeLit(typ ast.Expr, elements []ast.Expr) *ast.CompositeLit {
 	return &ast.CompositeLit{
 		Type: typ,
 		Elts: elements,
 	}
 }
+
+// Go doesn't allow line breaks in interpreted strings, unlike
+// raw `strings`, so those get escaped.
+func makeStringLit(value string) *ast.BasicLit {
+	if strings.HasPrefix(value, "\"") {
+		value = strings.NewReplacer("\n", `\n`, "\r", `\r`).Replace(value)
+	}
+
+	return makeBasicLit(token.STRING, value)
+}
+
+// Big integers and ratios are parsed at runtime, i.e.
+// core.BigInt("1000000000000000000000"), since they're mutable.
+func makeBigNumber(constructor, value string) *ast.CallExpr {
+	lit := makeBasicLit(token.STRING, strconv.Quote(value))
+	return makeFuncCall(makeSelectorExpr(ast.NewIdent("core"), ast.NewIdent(constructor)), []ast.Expr{lit})
+}
+
+// Integer literals too big for an int become big integers, as if
+// they had an N suffix.
+func fitsInt(value string) bool {
+	_, err := strconv.ParseInt(value, 0, 64)
+	return !errors.Is(err, strconv.ErrRange)
+}
+
+// Keywords are core.Keyword("name"), since those are just
+// strings, equal keywords are always identical.
+func makeKeyword(name string) *ast.CallExpr {
+	lit := makeBasicLit(token.STRING, strconv.Quote(name))
+	return makeFuncCall(makeSelectorExpr(ast.NewIdent("core"), ast.NewIdent("Keyword")), []ast.Expr{lit})
+}
+
+// {k1 v1 k2 v2} becomes a core.HashMap(k1, v1, k2, v2), a
+// persistent hash map
+func makeMap(nodes []parser.Node) *ast.CallExpr {
+	if len(nodes)%2 != 0 {
+		panic("map literal needs an even number of keys and values!")
+	}
+
+	seen := map[string]bool{}
+	elements := make([]ast.Expr, 0, len(nodes))
+
+	for i := 0; i < len(nodes); i += 2 {
+		switch nodes[i].Type() {
+		case parser.NodeKeyword, parser.NodeString, parser.NodeNumber, parser.NodeBool, parser.NodeNil:
+			key := nodes[i].String()
+			if seen[key] {
+				panic("duplicate key in map literal: " + key)
+			}
+			seen[key] = true
+		}
+
+		elements = append(elements, EvalExpr(nodes[i]), EvalExpr(nodes[i+1]))
+	}
+
+	return coreCall("HashMap", elements...)
+}
+
+func makeMapType(key, value ast.Expr) *ast.MapType {
+	return &ast.MapType{
+		Key:   key,
+		Value: value,
+	}
+}
+
+func makeKeyValueExpr(key, value ast.Expr) *ast.KeyValueExpr {
+	return &ast.KeyValueExpr{
+		Key:   key,
+		Value: value,
+	}
+}
+
+// #{1 2 3} becomes a core.HashSet(1, 2, 3), a persistent hash set
+func makeSet(nodes []parser.Node) *ast.CallExpr {
+	seen := map[string]bool{}
+	elements := make([]ast.Expr, len(nodes))
+
+	for i, node := range nodes {
+		switch node.Type() {
+		case parser.NodeKeyword, parser.NodeString, parser.NodeNumber, parser.NodeBool, parser.NodeNil:
+			if seen[node.String()] {
+				panic("duplicate element in set literal: " + node.String())
+			}
+			seen[node.String()] = true
+		}
+
+		elements[i] = EvalExpr(node)
+	}
+
+	return coreCall("HashSet", elements...)
+}
+
+// Compiled regex literals by their pattern
+var regexIdents = map[string]*ast.Ident{}
+
+// #"pattern" is compiled once, into a package-level
+// var GEN0 = regexp.MustCompile(`pattern`)
+func makeRegex(pattern string) *ast.Ident {
+	if ident, ok := regexIdents[pattern]; ok {
+		return ident
+	}
+
+	lit := "`" + pattern + "`"
+	if strings.Contains(pattern, "`") {
+		lit = strconv.Quote(pattern)
+	}
+
+	if _, err := regexp.Compile(pattern); err != nil {
+		panic(fmt.Sprintf("invalid regex literal: %s", err))
+	}
+
+	ident := generateIdent()
+	compile := makeFuncCall(makeSelectorExpr(ast.NewIdent("regexp"), ast.NewIdent("MustCompile")), []ast.Expr{makeBasicLit(token.STRING, lit)})
+	spec := makeValueSpec([]*ast.Ident{ident}, []ast.Expr{compile}, nil)
+
+	packageDecls = append(packageDecls, makeGeneralDecl(token.VAR, []ast.Spec{spec}))
+	regexIdents[pattern] = ident
+
+	return ident
+}
@@ -0,0 +1,58 @@
+package generator
+
+import "testing"
+
+func TestMapLiterals(t *testing.T) {
+	runCompileTests(t, []compileTest{
+		{"keyword", `(def f (fn [] :a))`, ""},
+		{"map", `(def m {:a 1 "b" [2 3] 4 {:c nil}})`, ""},
+		{"empty map", `(def m {})`, ""},
+		{"computed keys", `(def f (fn [k] {k 1 (+ k 1) 2}))`, ""},
+		{"get", `(def f (fn [m] (+ (get :a m) (get :b 0 m))))`, ""},
+		{"odd", `(def m {:a 1 :b})`, "even number of keys and values"},
+		{"duplicate key", `(def m {:a 1 :a 2})`, "duplicate key in map literal: :a"},
+	})
+}
+
+func TestSetAndRegexLiterals(t *testing.T) {
+	runCompileTests(t, []compileTest{
+		{"set", `(def s #{1 :a "b"})`, ""},
+		{"get set", `(def f (fn [x] (get x #{1 2})))`, ""},
+		{"duplicate element", `(def s #{12 12})`, "duplicate element in set literal: 12"},
+		{"regex", `(def f (fn [] (fmt/sprint #"a\d+")))`, ""},
+		{"same regex twice", `(def f (fn [] (fmt/sprint #"a+" #"a+")))`, ""},
+		{"regex with backquote", "(def r #\"`\")", ""},
+		{"invalid regex", `(def r #"a(")`, "invalid regex literal"},
+	})
+}
+
+func TestAnonymousFns(t *testing.T) {
+	runCompileTests(t, []compileTest{
+		{"one arg", `(def f #(+ % 1))`, ""},
+		{"numbered args", `(def f #(fmt/sprint %1 %3))`, ""},
+		{"rest args", `(def f #(fmt/sprint % %&))`, ""},
+		{"discarded form", `(def f (fn [] #_ (undefined-fn) 1))`, ""},
+	})
+}
+
+func TestCharsAndStrings(t *testing.T) {
+	runCompileTests(t, []compileTest{
+		{"chars", `(def f (fn [] (fmt/sprint \a \newline \λ \' \\)))`, ""},
+		{"raw string", "(def s `a\\d\n\"b\"`)", ""},
+		{"multi-line string", "(def s \"a\nb\")", ""},
+		{"escapes", `(def s "\t\x41é")`, ""},
+	})
+}
+
+func TestNumberAndBoolLiterals(t *testing.T) {
+	runCompileTests(t, []compileTest{
+		{"ints", `(def f (fn [] (fmt/sprint 0x1F 0o17 0b101 1_000 -5)))`, ""},
+		{"floats", `(def f (fn [] (fmt/sprint 1.5e3 0x1p-2 2i)))`, ""},
+		{"big", `(def f (fn [] (fmt/sprint 123456789012345678901234567890N 1/3)))`, ""},
+		{"int too big", `(def f (fn [] (+ 123456789012345678901234567890 1)))`, ""},
+		{"big arithmetic", `(def f (fn [n] (* 2N n 1/2)))`, ""},
+		{"bool and nil", `(def f (fn [] (fmt/sprint true false nil)))`, ""},
+		{"case on bool", `(def f (fn [x] (case x true "yes" false "no" nil "nothing")))`, ""},
+		{"duplicate nil", `(def m {nil 1 nil 2})`, "duplicate key in map literal: nil"},
+	})
+}
@@ -0,0 +1,317 @@
+package generator
+
+import (
+	"github.com/jcla1/gisp/parser"
+	h "github.com/jcla1/gisp/generator/helpers"
+	"go/ast"
+	"go/token"
+)
+
+type loopLabel struct {
+	ident *ast.Ident
+	used  bool
+}
+
+// The loops whose body is currently being generated, the
+// innermost one is the target of break and continue.
+var loopLabels []*loopLabel
+
+func checkRangeBinding(node *parser.CallNode, name string) bool {
+	if !isCallTo(node, name) {
+		return false
+	}
+
+	if len(node.Args) < 1 {
+		panic(name + " needs a binding vector!")
+	}
+
+	binding, ok := node.Args[0].(*parser.VectorNode)
+	if !ok || len(binding.Nodes) != 2 {
+		panic(name + " binding should look like: [x coll]")
+	}
+
+	switch b := binding.Nodes[0].(type) {
+	case *parser.IdentNode:
+		return true
+	case *parser.VectorNode:
+		if name == "dotimes" || len(b.Nodes) < 1 || len(b.Nodes) > 2 {
+			panic("invalid " + name + " binding!")
+		}
+
+		for _, ident := range b.Nodes {
+			if ident.Type() != parser.NodeIdent {
+				panic(name + " can only bind identifiers!")
+			}
+		}
+
+		return true
+	}
+
+	panic(name + " can only bind identifiers!")
+}
+
+func checkDotimesArgs(node *parser.CallNode) bool {
+	return checkRangeBinding(node, "dotimes")
+}
+
+func checkDoseqArgs(node *parser.CallNode) bool {
+	return checkRangeBinding(node, "doseq")
+}
+
+func checkRangeOverArgs(node *parser.CallNode) bool {
+	return checkRangeBinding(node, "range-over")
+}
+
+// getRangeIdents returns the identifiers bound by a range binding,
+// those the body doesn't use become _, as Go rejects unused ones.
+func getRangeIdents(node parser.Node, body []parser.Node) []ast.Expr {
+	nodes := []parser.Node{node}
+	if vect, ok := node.(*parser.VectorNode); ok {
+		nodes = vect.Nodes
+	}
+
+	idents := make([]ast.Expr, len(nodes))
+	for i, n := range nodes {
+		name := n.(*parser.IdentNode).Ident
+		if searchForIdent(body, name) {
+			idents[i] = makeLocalIdent(name)
+		} else {
+			idents[i] = ast.NewIdent("_")
+		}
+	}
+
+	return idents
+}
+
+func isBlank(x ast.Expr) bool {
+	ident, ok := x.(*ast.Ident)
+	return ok && ident.Name == "_"
+}
+
+// (dotimes [i n] ...) counts i from 0 up to n (exclusive)
+func makeDotimesStmt(node *parser.CallNode) ast.Stmt {
+	binding := node.Args[0].(*parser.VectorNode).Nodes
+	ident := makeLocalIdent(binding[0].(*parser.IdentNode).Ident)
+	limit := generateIdent()
+
+	init := makeAssignStmt(h.E(ident, limit), h.E(makeBasicLit(token.INT, "0"), EvalExpr(binding[1])), token.DEFINE)
+	cond := makeFuncCall(makeSelectorExpr(ast.NewIdent("core"), ast.NewIdent("LT")), h.E(ident, limit))
+	post := makeIncDecStmt(ident, token.INC)
+
+	return makeLabeledLoop(node.Args[1:], func(body *ast.BlockStmt) ast.Stmt {
+		return makeForStmt(init, post, cond, body)
+	})
+}
+
+// (doseq [x coll] ...) ranges over the values of coll, while
+// (doseq [[k v] coll] ...) also binds the index or key.
+// Since coll is a core.Any, we have to go through core.Range.
+func makeDoseqStmt(node *parser.CallNode) ast.Stmt {
+	binding := node.Args[0].(*parser.VectorNode).Nodes
+	idents := getRangeIdents(binding[0], node.Args[1:])
+
+	var key, value ast.Expr
+	if len(idents) == 1 {
+		key, value = ast.NewIdent("_"), idents[0]
+	} else {
+		key, value = idents[0], idents[1]
+	}
+
+	if isBlank(value) {
+		key, value = nil, nil
+		if len(idents) == 2 && !isBlank(idents[0]) {
+			key = idents[0]
+		}
+	}
+
+	coll := makeFuncCall(makeSelectorExpr(ast.NewIdent("core"), ast.NewIdent("Range")), h.E(EvalExpr(binding[1])))
+
+	return makeLabeledLoop(node.Args[1:], func(body *ast.BlockStmt) ast.Stmt {
+		return makeRangeStmt(key, value, coll, body)
+	})
+}
+
+// (range-over [x xs] ...) is Go's plain range, so it needs a
+// typed slice, map, string or channel (i.e. from a Go call). Like
+// doseq, x is bound to the elements, (range-over [[i x] xs] ...)
+// binds the index or key too. [[x] ch] binds Go's single range
+// variable, which is what ranging over a channel needs.
+func makeRangeOverStmt(node *parser.CallNode) ast.Stmt {
+	binding := node.Args[0].(*parser.VectorNode).Nodes
+	idents := getRangeIdents(binding[0], node.Args[1:])
+
+	var key, value ast.Expr
+	switch {
+	case binding[0].Type() == parser.NodeIdent:
+		key, value = ast.NewIdent("_"), idents[0]
+	case len(idents) == 1:
+		key = idents[0]
+	default:
+		key, value = idents[0], idents[1]
+	}
+
+	if value != nil && isBlank(value) {
+		value = nil
+	}
+	if key != nil && isBlank(key) && value == nil {
+		key = nil
+	}
+
+	coll := EvalExpr(binding[1])
+
+	return makeLabeledLoop(node.Args[1:], func(body *ast.BlockStmt) ast.Stmt {
+		return makeRangeStmt(key, value, coll, body)
+	})
+}
+
+// makeLabeledLoop generates the loop body in statement position,
+// labeling the loop only when break or continue refer to it.
+func makeLabeledLoop(nodes []parser.Node, loop func(*ast.BlockStmt) ast.Stmt) ast.Stmt {
+	label := &loopLabel{ident: generateIdent()}
+
+	loopLabels = append(loopLabels, label)
+	body := makeBlockStmt(evalStmts(nodes))
+	loopLabels = loopLabels[:len(loopLabels)-1]
+
+	if !label.used {
+		return loop(body)
+	}
+
+	return makeLabeledStmt(label.ident, loop(body))
+}
+
+func isLoopStmt(node *parser.CallNode) bool {
+	return checkDotimesArgs(node) || checkDoseqArgs(node) || checkRangeOverArgs(node)
+}
+
+func makeLoopStmt(node *parser.CallNode) ast.Stmt {
+	pushLocals(boundNames(node.Args[0].(*parser.VectorNode).Nodes[0]))
+	defer popLocals()
+
+	switch node.Callee.(*parser.IdentNode).Ident {
+	case "dotimes":
+		return makeDotimesStmt(node)
+	case "doseq":
+		return makeDoseqStmt(node)
+	default:
+		return makeRangeOverStmt(node)
+	}
+}
+
+// As an expression, loops evaluate to nil
+func makeLoopFunc(node *parser.CallNode) *ast.CallExpr {
+	return makeClosureCall(h.S(makeLoopStmt(node), makeReturnStmt(h.E(ast.NewIdent("nil")))))
+}
+
+var branchMap = map[string]token.Token{
+	"break":    token.BREAK,
+	"continue": token.CONTINUE,
+}
+
+func isBranch(node *parser.CallNode) bool {
+	if node.Callee.Type() != parser.NodeIdent {
+		return false
+	}
+
+	_, ok := branchMap[node.Callee.(*parser.IdentNode).Ident]
+	return ok
+}
+
+func makeBranch(node *parser.CallNode) ast.Stmt {
+	if len(node.Args) != 0 {
+		panic("break/continue don't take any arguments!")
+	}
+
+	if len(loopLabels) == 0 {
+		panic("break/continue can only be used within a loop!")
+	}
+
+	label := loopLabels[len(loopLabels)-1]
+	label.used = true
+
+	return makeBranchStmt(branchMap[node.Callee.(*parser.IdentNode).Ident], label.ident)
+}
+
+func evalStmts(nodes []parser.Node) []ast.Stmt {
+	out := h.EmptyS()
+
+	for _, node := range nodes {
+		out = append(out, evalStmt(node)...)
+	}
+
+	return out
+}
+
+// evalStmt generates a form whose value isn't needed, like in a
+// loop body. Control flow then becomes Go statements instead of
+// closures, which lets break and continue reach their loop.
+func evalStmt(node parser.Node) []ast.Stmt {
+	n, ok := node.(*parser.CallNode)
+	if !ok {
+		return h.S(makeAssignStmt(h.E(ast.NewIdent("_")), h.E(EvalExpr(node)), token.ASSIGN))
+	}
+
+	switch {
+	case isBranch(n):
+		return h.S(makeBranch(n))
+
+	case isLoopStmt(n):
+		return h.S(makeLoopStmt(n))
+
+	case checkLetArgs(n):
+		bindings := makeLetBindings(n)
+		defer popLocals()
+		return h.S(makeBlockStmt(append(bindings, evalStmts(n.Args[1:])...)))
+
+	case checkIfArgs(n):
+		var elseBody ast.Stmt
+		if len(n.Args) > 2 {
+			elseBody = makeBlockStmt(evalStmt(n.Args[2]))
+		}
+
+		return h.S(makeIfStmt(EvalExpr(n.Args[0]), makeBlockStmt(evalStmt(n.Args[1])), elseBody))
+
+	case checkWhenArgs(n):
+		cond := EvalExpr(n.Args[0])
+		if isCallTo(n, "unless") {
+			cond = makeUnaryExpr(token.NOT, cond)
+		}
+
+		return h.S(makeIfStmt(cond, makeBlockStmt(evalStmts(n.Args[1:])), nil))
+
+	case checkCondArgs(n):
+		if len(n.Args) == 0 {
+			return h.EmptyS()
+		}
+		return h.S(makeCondStmt(n.Args))
+
+	case checkDoArgs(n):
+		return evalStmts(n.Args)
+	}
+
+	expr := EvalExpr(n)
+	if _, ok := expr.(*ast.CallExpr); ok {
+		return h.S(makeExprStmt(expr))
+	}
+
+	return h.S(makeAssignStmt(h.E(ast.NewIdent("_")), h.E(expr), token.ASSIGN))
+}
+
+// In statement position cond becomes an if/else chain,
+// a switch would capture an unlabeled break.
+func makeCondStmt(clauses []parser.Node) ast.Stmt {
+	if len(clauses) == 0 {
+		return nil
+	}
+
+	if isElseKeyword(clauses[0]) {
+		return makeBlockStmt(evalStmt(clauses[1]))
+	}
+
+	ifStmt := makeIfStmt(EvalExpr(clauses[0]), makeBlockStmt(evalStmt(clauses[1])), nil)
+	if elseStmt := makeCondStmt(clauses[2:]); elseStmt != nil {
+		ifStmt.Else = elseStmt
+	}
+
+	return ifStmt
+}
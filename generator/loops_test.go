@@ -0,0 +1,29 @@
+package generator
+
+import "testing"
+
+func TestLoops(t *testing.T) {
+	runCompileTests(t, []compileTest{
+		{"dotimes", `(def f (fn [n] (dotimes [i n] (fmt/println i))))`, ""},
+		{"doseq", `(def f (fn [coll] (doseq [x coll] (fmt/println x))))`, ""},
+		{"doseq key", `(def f (fn [coll] (doseq [[k v] coll] (fmt/println k v))))`, ""},
+		{"range-over", `(def f (fn [] (range-over [s (strings/fields "a b")] (fmt/println s))))`, ""},
+		{"unused key", `(def f (fn [coll] (doseq [[k v] coll] (fmt/println v))))`, ""},
+		{"unused value", `(def f (fn [coll] (doseq [[k v] coll] (fmt/println k))))`, ""},
+		{"unused element", `(def f (fn [n coll] (doseq [x coll] (fmt/println n))))`, ""},
+		{"used in map", `(def f (fn [coll] (doseq [x coll] (fmt/println {:x x}))))`, ""},
+		{"range-over index", `(def f (fn [] (range-over [[i s] (strings/fields "a b")] (fmt/println i s))))`, ""},
+		{"range-over unused", `(def f (fn [] (range-over [s (strings/fields "a b")] (fmt/println "x"))))`, ""},
+		{"range-over single", `(def f (fn [] (range-over [[i] (strings/fields "a b")] (fmt/println i))))`, ""},
+		{"break", `(def f (fn [coll] (doseq [x coll] (when (= x 1) (break)) (fmt/println x))))`, ""},
+		{"continue in cond", `(def f (fn [coll] (doseq [x coll] (cond (= x 1) (continue) :else (fmt/println x)))))`, ""},
+		{"nested break", `(def f (fn [n] (dotimes [i n] (dotimes [j n] (when (= i j) (break))))))`, ""},
+		{"loop as value", `(def f (fn [n] (let [[x (dotimes [i n] (fmt/println i))]] x)))`, ""},
+		{"no binding", `(def f (fn [n] (dotimes)))`, "binding vector"},
+		{"not a vector", `(def f (fn [n] (dotimes n (fmt/println n))))`, "binding should look like"},
+		{"bad binding", `(def f (fn [n] (dotimes [i] (fmt/println i))))`, "binding should look like"},
+		{"break outside loop", `(def f (fn [] (break)))`, "statement position"},
+		{"break as value", `(def f (fn [n] (dotimes [i n] (fmt/println (break)))))`, "statement position"},
+		{"break with args", `(def f (fn [n] (dotimes [i n] (break 1))))`, "don't take any arguments"},
+	})
+}
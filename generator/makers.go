@@ -30,7 +30,8 @@ func makeIfStmtFunc(node *parser.CallNode) ast.Expr {
 }
 
 func makeLetFun(node *parser.CallNode) ast.Expr {
-	bindings := makeBindings(node.Args[0].(*parser.VectorNode), token.DEFINE)
+	bindings := makeLetBindings(node)
+	defer popLocals()
 
 	body := append(bindings, wrapExprsWithStmt(EvalExprs(node.Args[1:]))...)
 	body[len(body)-1] = makeReturnStmt(h.E(body[len(body)-1].(*ast.ExprStmt).X))
@@ -42,12 +43,59 @@ func makeLetFun(node *parser.CallNode) ast.Expr {
 	return makeFuncCall(fn, h.EmptyE())
 }
 
+// makeLetBindings binds the names of a let, those its body set!s
+// are core.Any.
+func makeLetBindings(node *parser.CallNode) []ast.Stmt {
+	set := setLocals(node.Args[1:])
+	bindings := makeBindings(node.Args[0].(*parser.VectorNode), token.DEFINE)
+	return declareAsAny(bindings, func(name string) bool { return set[name] })
+}
+
+// makeBindings with token.DEFINE brings the names into scope,
+// the caller has to popLocals once the body is generated.
 func makeBindings(bindings *parser.VectorNode, assignmentType token.Token) []ast.Stmt {
-	assignments := make([]ast.Stmt, len(bindings.Nodes))
+	assignments := make([]ast.Stmt, 0, len(bindings.Nodes))
 
-	for i, bind := range bindings.Nodes {
+	names := []string{}
+	for _, bind := range bindings.Nodes {
 		b := bind.(*parser.VectorNode)
+		for _, node := range b.Nodes[:len(b.Nodes)-1] {
+			names = append(names, boundNames(node)...)
+		}
+	}
+	checkNameCollisions(names)
+
+	// the names come into scope one binding at a time, after its
+	// value, which still sees what they shadow: (let [[x (+ x 1)]] x)
+	if assignmentType == token.DEFINE {
+		pushLocals(nil)
+	}
+	bind := func(nodes []parser.Node) {
+		if assignmentType != token.DEFINE {
+			return
+		}
+		for _, node := range nodes {
+			addLocals(boundNames(node))
+		}
+	}
+
+	for _, binding := range bindings.Nodes {
+		b := binding.(*parser.VectorNode)
+
+		if isPattern(b.Nodes[0]) {
+			if len(b.Nodes) != 2 {
+				panic("destructuring binding should look like: [pattern value]")
+			}
+
+			value := EvalExpr(b.Nodes[1])
+			bind(b.Nodes[:1])
+			assignments = append(assignments, makeDestructuring(b.Nodes[0], value, assignmentType)...)
+			continue
+		}
+
 		idents := b.Nodes[:len(b.Nodes)-1]
+		value := EvalExpr(b.Nodes[len(b.Nodes)-1])
+		bind(idents)
 
 		vars := make([]ast.Expr, len(idents))
 
@@ -55,16 +103,23 @@ func makeBindings(bindings *parser.VectorNode, assignmentType token.Token) []ast
 			vars[j] = makeIdomaticSelector(ident.(*parser.IdentNode).Ident)
 		}
 
-		assignments[i] = makeAssignStmt(vars, h.E(EvalExpr(b.Nodes[len(b.Nodes)-1])), assignmentType)
+		assignments = append(assignments, makeAssignStmt(vars, h.E(value), assignmentType))
 	}
 
 	return assignments
 }
 
 func mainable(fn *ast.FuncLit) {
+	// main can't return an error, so a failing try-err just panics
+	if fn.Type.Results.List[0].Names != nil {
+		fn.Body.List = fn.Body.List[1:]
+	}
 	fn.Type.Results = nil
 
-	returnStmt := fn.Body.List[len(fn.Body.List)-1].(*ast.ReturnStmt)
+	returnStmt, ok := fn.Body.List[len(fn.Body.List)-1].(*ast.ReturnStmt)
+	if !ok {
+		panic("main can't recur!")
+	}
 	fn.Body.List[len(fn.Body.List)-1] = makeExprStmt(returnStmt.Results[0])
 
 	// return fn
@@ -0,0 +1,396 @@
+package generator
+
+import (
+	"github.com/jcla1/gisp/parser"
+	h "github.com/jcla1/gisp/generator/helpers"
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+type union struct {
+	name     string
+	variants []string
+}
+
+type variant struct {
+	union  *union
+	fields []string
+}
+
+// The unions declared by defunion and their variants, by name.
+var (
+	unions   map[string]*union
+	variants map[string]*variant
+)
+
+// registerUnion records the variants of a defunion, so match
+// knows them before the union is generated.
+func registerUnion(node *parser.CallNode) []string {
+	if len(node.Args) < 2 || node.Args[0].Type() != parser.NodeIdent {
+		panic("defunion should look like: (defunion Shape (Circle r) (Rect w h))")
+	}
+
+	u := &union{name: node.Args[0].(*parser.IdentNode).Ident}
+	declared := []string{u.name}
+
+	for _, v := range node.Args[1:] {
+		name, fields := getVariant(v)
+		fieldNames := []string{}
+		for _, field := range fields {
+			if ident, ok := field.(*parser.IdentNode); ok {
+				fieldNames = append(fieldNames, ident.Ident)
+			}
+		}
+
+		u.variants = append(u.variants, name.Ident)
+		variants[name.Ident] = &variant{union: u, fields: fieldNames}
+		declared = append(declared, name.Ident, "->"+name.Ident)
+	}
+
+	unions[u.name] = u
+	return declared
+}
+
+// A variant is either (Circle r ^float64) or just Empty
+func getVariant(node parser.Node) (*parser.IdentNode, []parser.Node) {
+	switch v := node.(type) {
+	case *parser.IdentNode:
+		return v, nil
+	case *parser.CallNode:
+		if name, ok := v.Callee.(*parser.IdentNode); ok {
+			return name, v.Args
+		}
+	}
+
+	panic("invalid variant: " + node.String())
+}
+
+// evalDefunion declares a sealed interface, every variant being a
+// defstruct implementing it:
+//
+//	(defunion Shape (Circle r) (Rect w h))
+//
+//	type Shape interface {
+//		isShape()
+//	}
+//
+//	type Circle struct {
+//		R core.Any
+//	}
+//
+//	func (Circle) isShape() {}
+//	...
+func evalDefunion(node *parser.CallNode) []ast.Decl {
+	name := node.Args[0].(*parser.IdentNode)
+	marker := ast.NewIdent(Mangle("is-"+name.Ident, false))
+	markerType := makeFuncType(nil, makeFieldList(nil))
+
+	iface := &ast.InterfaceType{Methods: makeFieldList([]*ast.Field{makeField(h.I(marker), markerType)})}
+	decls := []ast.Decl{makeGeneralDecl(token.TYPE, []ast.Spec{&ast.TypeSpec{Name: makeIdomaticIdent(name.Ident), Type: iface}})}
+
+	for _, v := range node.Args[1:] {
+		variantName, fields := getVariant(v)
+		def := &parser.CallNode{
+			NodeType: parser.NodeCall,
+			Callee:   parser.NewIdentNode("defstruct"),
+			Args:     []parser.Node{variantName, &parser.VectorNode{NodeType: parser.NodeVector, Nodes: fields}},
+		}
+
+		decls = append(decls, evalDefstruct(def)...)
+		decls = append(decls, &ast.FuncDecl{
+			Recv: makeFieldList([]*ast.Field{makeField(nil, makeIdomaticIdent(variantName.Ident))}),
+			Name: marker,
+			Type: markerType,
+			Body: makeBlockStmt(h.EmptyS()),
+		})
+	}
+
+	return decls
+}
+
+func checkMatchArgs(node *parser.CallNode) bool {
+	if !isCallTo(node, "match") {
+		return false
+	}
+
+	if len(node.Args) < 3 || len(node.Args)%2 != 1 {
+		panic("match needs an expression followed by pattern/expression pairs!")
+	}
+
+	for i := 1; i < len(node.Args); i += 2 {
+		if isElseKeyword(node.Args[i]) && i != len(node.Args)-2 {
+			panic("match's :else has to be the last clause!")
+		}
+	}
+
+	checkExhaustive(node)
+	return true
+}
+
+func isWildcard(pattern parser.Node) bool {
+	if isElseKeyword(pattern) {
+		return true
+	}
+
+	ident, ok := pattern.(*parser.IdentNode)
+	return ok && variants[ident.Ident] == nil
+}
+
+// getVariantPattern returns the variant a pattern like (Circle r)
+// or Empty matches, and the patterns of its fields.
+func getVariantPattern(pattern parser.Node) (*variant, string, []parser.Node) {
+	switch p := pattern.(type) {
+	case *parser.IdentNode:
+		if v := variants[p.Ident]; v != nil {
+			return v, p.Ident, nil
+		}
+	case *parser.CallNode:
+		if name, ok := p.Callee.(*parser.IdentNode); ok && variants[name.Ident] != nil {
+			v := variants[name.Ident]
+			if len(p.Args) != len(v.fields) {
+				panic("wrong number of fields in pattern: " + p.String())
+			}
+			return v, name.Ident, p.Args
+		}
+	}
+
+	return nil, "", nil
+}
+
+// A match has to either end in a catch all clause, or cover every
+// variant of a union with patterns that match any of its values.
+func checkExhaustive(node *parser.CallNode) {
+	var u *union
+	covered := map[string]bool{}
+
+	for i := 1; i < len(node.Args); i += 2 {
+		if isWildcard(node.Args[i]) {
+			return
+		}
+	}
+
+	for i := 1; i < len(node.Args); i += 2 {
+		v, name, fields := getVariantPattern(node.Args[i])
+		if v == nil || (u != nil && v.union != u) {
+			u = nil
+			break
+		}
+		u = v.union
+
+		irrefutable := true
+		for _, field := range fields {
+			irrefutable = irrefutable && isWildcard(field)
+		}
+		covered[name] = covered[name] || irrefutable
+	}
+
+	if u == nil {
+		panic("non-exhaustive match, it needs a _ or :else clause: " + node.String())
+	}
+
+	missing := []string{}
+	for _, name := range u.variants {
+		if !covered[name] {
+			missing = append(missing, name)
+		}
+	}
+
+	if len(missing) > 0 {
+		panic("non-exhaustive match over " + u.name + ", missing: " + strings.Join(missing, ", "))
+	}
+}
+
+func makeMatchFunc(node *parser.CallNode) *ast.CallExpr {
+	return makeClosureCall(makeMatch(node, func(expr parser.Node) []ast.Stmt {
+		return h.S(makeReturnStmt(h.E(EvalExpr(expr))))
+	}))
+}
+
+// makeMatch tries the clauses in order, each pattern becoming
+// nested ifs that fall through to the next clause:
+//
+//	(match s (Circle r) (* r r) (Rect w h) (* w h))
+//
+//	GEN0 := s
+//	if GEN1, ok := GEN0.(Circle); ok {
+//		r := GEN1.R
+//		return core.MUL(r, r)
+//	}
+//	...
+//	panic(core.MatchError{Value: GEN0})
+func makeMatch(node *parser.CallNode, evalClause func(parser.Node) []ast.Stmt) []ast.Stmt {
+	value := generateIdent()
+	stmts := h.S(makeAssignStmt(h.E(value), h.E(EvalExpr(node.Args[0])), token.DEFINE))
+
+	for i := 1; i < len(node.Args); i += 2 {
+		pattern, expr := node.Args[i], node.Args[i+1]
+		names := patternNames(pattern)
+
+		pushLocals(names)
+		body := evalClause(expr)
+		popLocals()
+
+		stmts = append(stmts, matchPattern(pattern, value, expr, body)...)
+		if isWildcard(pattern) {
+			return stmts
+		}
+	}
+
+	noMatch := makeCompositeLit(makeSelectorExpr(ast.NewIdent("core"), ast.NewIdent("MatchError")), h.E(makeKeyValueExpr(ast.NewIdent("Value"), value)))
+	return append(stmts, makeExprStmt(makeFuncCall(ast.NewIdent("panic"), h.E(noMatch))))
+}
+
+// The identifiers a pattern binds
+func patternNames(pattern parser.Node) []string {
+	switch p := pattern.(type) {
+	case *parser.IdentNode:
+		if isWildcard(p) && p.Ident != "_" {
+			return []string{p.Ident}
+		}
+	case *parser.CallNode, *parser.VectorNode, *parser.MapNode:
+		names := []string{}
+		for _, sub := range subPatterns(pattern) {
+			names = append(names, patternNames(sub)...)
+		}
+		return names
+	}
+
+	return nil
+}
+
+func subPatterns(pattern parser.Node) []parser.Node {
+	switch p := pattern.(type) {
+	case *parser.CallNode:
+		return p.Args
+	case *parser.VectorNode:
+		subs := []parser.Node{}
+		for _, sub := range p.Nodes {
+			if !isIdent(sub, "&") {
+				subs = append(subs, sub)
+			}
+		}
+		return subs
+	case *parser.MapNode:
+		subs := []parser.Node{}
+		for i := 1; i < len(p.Nodes); i += 2 {
+			subs = append(subs, p.Nodes[i])
+		}
+		return subs
+	}
+
+	return nil
+}
+
+// matchPattern returns the statements running body, if the value
+// matches the pattern, binding the identifiers used by expr.
+func matchPattern(pattern parser.Node, value ast.Expr, expr parser.Node, body []ast.Stmt) []ast.Stmt {
+	if v, name, fields := getVariantPattern(pattern); v != nil {
+		typed := generateIdent()
+		inner := body
+		for i := len(fields) - 1; i >= 0; i-- {
+			field := makeSelectorExpr(typed, ast.NewIdent(Mangle(v.fields[i], true)))
+			inner = matchPattern(fields[i], field, expr, inner)
+		}
+
+		if !usesIdent(inner, typed) {
+			typed = ast.NewIdent("_")
+		}
+
+		ok := generateIdent()
+		assert := makeAssignStmt(h.E(typed, ok), h.E(makeTypeAssertion(value, makeIdomaticIdent(name))), token.DEFINE)
+		ifStmt := makeIfStmt(ok, makeBlockStmt(inner), nil)
+		ifStmt.Init = assert
+		return h.S(ifStmt)
+	}
+
+	switch p := pattern.(type) {
+	case *parser.IdentNode:
+		if p.Ident == "_" || !searchForIdent([]parser.Node{expr}, p.Ident) {
+			return body
+		}
+		return append(h.S(makeAssignStmt(h.E(makeLocalIdent(p.Ident)), h.E(value), token.DEFINE)), body...)
+
+	case *parser.KeywordNode:
+		if isElseKeyword(p) {
+			return body
+		}
+		return h.S(makeIfStmt(makeBinaryExpr(token.EQL, value, EvalExpr(p)), makeBlockStmt(body), nil))
+
+	case *parser.NumberNode:
+		return h.S(makeIfStmt(coreCall("EQ", value, EvalExpr(p)), makeBlockStmt(body), nil))
+
+	case *parser.StringNode, *parser.CharNode, *parser.BoolNode, *parser.NilNode:
+		return h.S(makeIfStmt(makeBinaryExpr(token.EQL, value, EvalExpr(p)), makeBlockStmt(body), nil))
+
+	case *parser.VectorNode:
+		return matchVector(p, value, expr, body)
+
+	case *parser.MapNode:
+		return matchMap(p, value, expr, body)
+	}
+
+	panic("invalid pattern: " + pattern.String())
+}
+
+func usesIdent(stmts []ast.Stmt, ident *ast.Ident) bool {
+	used := false
+	for _, stmt := range stmts {
+		ast.Inspect(stmt, func(n ast.Node) bool {
+			used = used || n == ident
+			return !used
+		})
+	}
+	return used
+}
+
+// [a b & rest] matches vectors of at least two elements
+func matchVector(p *parser.VectorNode, value ast.Expr, expr parser.Node, body []ast.Stmt) []ast.Stmt {
+	elems, rest := p.Nodes, parser.Node(nil)
+	for i, elem := range p.Nodes {
+		if isIdent(elem, "&") {
+			if i != len(p.Nodes)-2 {
+				panic("& has to be followed by the last pattern: " + p.String())
+			}
+			elems, rest = p.Nodes[:i], p.Nodes[i+1]
+			break
+		}
+	}
+
+	inner := body
+	if rest != nil {
+		inner = matchPattern(rest, coreCall("NthRest", value, makeIntLit(len(elems))), expr, inner)
+	}
+	for i := len(elems) - 1; i >= 0; i-- {
+		inner = matchPattern(elems[i], coreCall("Nth", value, makeIntLit(i)), expr, inner)
+	}
+
+	cond := coreCall("IsVectorOf", value, makeIntLit(len(elems)), ast.NewIdent(strconvBool(rest != nil)))
+	return h.S(makeIfStmt(cond, makeBlockStmt(inner), nil))
+}
+
+// {:name n} matches maps having the key :name
+func matchMap(p *parser.MapNode, value ast.Expr, expr parser.Node, body []ast.Stmt) []ast.Stmt {
+	if len(p.Nodes)%2 != 0 {
+		panic("map patterns need a pattern for every key: " + p.String())
+	}
+
+	inner := body
+	for i := len(p.Nodes) - 2; i >= 0; i -= 2 {
+		elem, found := generateIdent(), generateIdent()
+		lookup := makeAssignStmt(h.E(elem, found), h.E(coreCall("Find", value, EvalExpr(p.Nodes[i]))), token.DEFINE)
+
+		ifStmt := makeIfStmt(found, makeBlockStmt(matchPattern(p.Nodes[i+1], elem, expr, inner)), nil)
+		ifStmt.Init = lookup
+		inner = h.S(ifStmt)
+	}
+
+	return h.S(makeIfStmt(coreCall("IsMap", value), makeBlockStmt(inner), nil))
+}
+
+func strconvBool(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
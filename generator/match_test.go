@@ -0,0 +1,34 @@
+package generator
+
+import "testing"
+
+const shapes = `(defunion Shape (Circle r ^float64) (Rect w h) Empty)
+`
+
+func TestDefunion(t *testing.T) {
+	runCompileTests(t, []compileTest{
+		{"defunion", shapes + `(defn f [] (fmt/sprint (->Circle 1.5) (->Rect 1 2) (->Empty)))`, ""},
+		{"bad defunion", `(defunion Shape)`, "defunion should look like"},
+		{"bad variant", `(defunion Shape "Circle")`, "invalid variant"},
+	})
+}
+
+func TestMatch(t *testing.T) {
+	runCompileTests(t, []compileTest{
+		{"variants", shapes + `(defn area [s] (match s (Circle r) (* r r) (Rect w h) (* w h) Empty 0))`, ""},
+		{"wildcard fields", shapes + `(defn width [s] (match s (Rect w _) w _ 0))`, ""},
+		{"literals", `(defn f [x] (match x 1 "one" "two" 2 :a "a" nil "nil" :else "other"))`, ""},
+		{"vectors", `(defn f [x] (match x [] "empty" [a] a [a b & more] (fmt/sprint a b more) _ nil))`, ""},
+		{"maps", `(defn f [x] (match x {:name n :age 42} n {:name n} n _ nil))`, ""},
+		{"nested", shapes + `(defn f [x] (match x [(Circle r) (Rect 1 h)] (fmt/sprint r h) _ nil))`, ""},
+		{"tail call", shapes + `(defn f [s n] (match s (Circle r) (f (->Empty) (+ n 1)) _ n))`, ""},
+		{"not exhaustive", shapes + `(defn f [s] (match s (Circle r) r (Rect w h) w))`, "non-exhaustive match over Shape, missing: Empty"},
+		{"refutable field", shapes + `(defn f [s] (match s (Circle 1) 1 (Rect w h) w Empty 0))`, "missing: Circle"},
+		{"no catch all", `(defn f [x] (match x 1 "one"))`, "needs a _ or :else clause"},
+		{"odd", `(defn f [x] (match x 1))`, "pattern/expression pairs"},
+		{"else not last", `(defn f [x] (match x :else 1 2 3))`, "last clause"},
+		{"field count", shapes + `(defn f [s] (match s (Circle) 1 _ 0))`, "wrong number of fields in pattern"},
+		{"bad rest", `(defn f [x] (match x [a & b c] a _ nil))`, "& has to be followed by the last pattern"},
+		{"odd map", `(defn f [x] (match x {:a} 1 _ nil))`, "a pattern for every key"},
+	})
+}
@@ -0,0 +1,73 @@
+package generator
+
+import (
+	"github.com/jcla1/gisp/parser"
+	h "github.com/jcla1/gisp/generator/helpers"
+	"go/ast"
+	"go/token"
+	"strconv"
+)
+
+func checkDefmultiArgs(node *parser.CallNode) {
+	if len(node.Args) != 2 || node.Args[0].Type() != parser.NodeIdent {
+		panic("defmulti should look like: (defmulti name dispatch-fn)")
+	}
+}
+
+// evalDefmulti declares the method table of a multimethod and the
+// function calling it, the table being name-methods, so that
+// defmethod can add methods from other namespaces:
+//
+//	(defmulti area :shape)
+//
+//	var areaMethods = core.NewMultiFn("area", core.Keyword("shape"))
+//
+//	func area(args ...core.Any) core.Any {
+//		return areaMethods.Call(args...)
+//	}
+func evalDefmulti(node *parser.CallNode) []ast.Decl {
+	checkDefmultiArgs(node)
+	name := node.Args[0].(*parser.IdentNode).Ident
+	methods := makeIdomaticIdent(name + "-methods")
+
+	newMulti := makeFuncCall(makeSelectorExpr(ast.NewIdent("core"), ast.NewIdent("NewMultiFn")), h.E(
+		makeStringLit(strconv.Quote(name)),
+		EvalExpr(node.Args[1]),
+	))
+	table := makeGeneralDecl(token.VAR, []ast.Spec{makeValueSpec(h.I(methods), h.E(newMulti), nil)})
+
+	args := ast.NewIdent("args")
+	call := makeFuncCall(makeSelectorExpr(methods, ast.NewIdent("Call")), h.E(args))
+	call.Ellipsis = 1
+
+	params := makeFieldList([]*ast.Field{makeField(h.I(args), makeEllipsis(anyType))})
+	results := makeFieldList([]*ast.Field{makeField(nil, anyType)})
+	fn := makeFuncLit(makeFuncType(results, params), makeBlockStmt(h.S(makeReturnStmt(h.E(call)))))
+
+	return []ast.Decl{table, makeFunDeclFromFuncLit(makeIdomaticIdent(name), fn)}
+}
+
+// evalDefmethod adds a method to a multimethod, when initializing
+// the package:
+//
+//	(defmethod area :circle [c] (* 3 (:r c) (:r c)))
+//
+//	var _ = areaMethods.AddMethod(core.Keyword("circle"), func(c core.Any) core.Any {
+//		...
+//	})
+func evalDefmethod(node *parser.CallNode) ast.Decl {
+	if len(node.Args) < 4 || node.Args[0].Type() != parser.NodeIdent || node.Args[2].Type() != parser.NodeVector {
+		panic("defmethod should look like: (defmethod name dispatch-value [args] body)")
+	}
+
+	fn := &parser.CallNode{
+		NodeType: parser.NodeCall,
+		Callee:   parser.NewIdentNode("fn"),
+		Args:     node.Args[2:],
+	}
+
+	methods := makeIdomaticSelector(node.Args[0].(*parser.IdentNode).Ident + "-methods")
+	add := makeFuncCall(makeSelectorExpr(methods, ast.NewIdent("AddMethod")), h.E(EvalExpr(node.Args[1]), makeFunc(fn, "")))
+
+	return makeGeneralDecl(token.VAR, []ast.Spec{makeValueSpec(h.I(ast.NewIdent("_")), h.E(add), nil)})
+}
@@ -7,7 +7,19 @@ import (
 )
 
 var (
-	callableOperators = []string{">", ">=", "<", "<=", "=", "+", "-", "*", "/", "mod"}
+	// the operators that are calls to core, by their Go names
+	callableOperators = map[string]string{
+		">":   "GT",
+		">=":  "GTEQ",
+		"<":   "LT",
+		"<=":  "LTEQ",
+		"=":   "EQ",
+		"+":   "ADD",
+		"-":   "SUB",
+		"*":   "MUL",
+		"/":   "DIV",
+		"mod": "MOD",
+	}
 	logicOperatorMap  = map[string]token.Token{
 		"and": token.LAND,
 		"or":  token.LOR,
@@ -15,6 +27,9 @@ var (
 
 	unaryOperatorMap = map[string]token.Token{
 		"!": token.NOT,
+		"&": token.AND,
+		// only with a single argument, otherwise it multiplies
+		"*": token.MUL,
 	}
 )
 
@@ -23,9 +38,8 @@ func isCallableOperator(node *parser.CallNode) bool {
 		return false
 	}
 
-	ident := node.Callee.(*parser.IdentNode).Ident
-
-	return isInSlice(ident, callableOperators)
+	_, ok := callableOperators[node.Callee.(*parser.IdentNode).Ident]
+	return ok
 }
 
 // We handle comparisons as a call to some go code, since you can only
@@ -34,35 +48,12 @@ func isCallableOperator(node *parser.CallNode) bool {
 func makeNAryCallableExpr(node *parser.CallNode) *ast.CallExpr {
 	op := node.Callee.(*parser.IdentNode).Ident
 	args := EvalExprs(node.Args)
-	var selector string
-
-	// TODO: abstract this away into a map!!!
-	switch op {
-	case ">":
-		selector = "GT"
-	case ">=":
-		selector = "GTEQ"
-	case "<":
-		selector = "LT"
-	case "<=":
-		selector = "LTEQ"
-	case "=":
-		selector = "EQ"
-	case "+":
-		selector = "ADD"
-	case "-":
-		selector = "SUB"
-	case "*":
-		selector = "MUL"
-	case "/":
-		selector = "DIV"
-	case "mod":
-		if len(node.Args) > 2 {
-			panic("can't calculate modulo with more than 2 arguments!")
-		}
-		selector = "MOD"
+
+	if op == "mod" && len(node.Args) > 2 {
+		panic("can't calculate modulo with more than 2 arguments!")
 	}
 
+	selector := callableOperators[op]
 	return makeFuncCall(makeSelectorExpr(ast.NewIdent("core"), ast.NewIdent(selector)), args)
 }
 
@@ -105,7 +96,12 @@ func isUnaryOperator(node *parser.CallNode) bool {
 		return false
 	}
 
-	_, ok := unaryOperatorMap[node.Callee.(*parser.IdentNode).Ident]
+	ident := node.Callee.(*parser.IdentNode).Ident
+	_, ok := unaryOperatorMap[ident]
+
+	if ident == "*" && len(node.Args) != 1 {
+		return false
+	}
 
 	if len(node.Args) != 1 && ok {
 		panic("unary expression takes, exactly, one argument!")
@@ -114,6 +110,22 @@ func isUnaryOperator(node *parser.CallNode) bool {
 	return ok
 }
 
+// (* p) dereferences p
+func makeUnaryOperatorExpr(node *parser.CallNode) ast.Expr {
+	op := unaryOperatorMap[node.Callee.(*parser.IdentNode).Ident]
+	if op == token.MUL {
+		return makeStarExpr(EvalExpr(node.Args[0]))
+	}
+
+	return makeUnaryExpr(op, EvalExpr(node.Args[0]))
+}
+
+func makeStarExpr(x ast.Expr) *ast.StarExpr {
+	return &ast.StarExpr{
+		X: x,
+	}
+}
+
 func makeUnaryExpr(op token.Token, x ast.Expr) *ast.UnaryExpr {
 	return &ast.UnaryExpr{
 		X:  x,
@@ -0,0 +1,40 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestArithmetic(t *testing.T) {
+	runCompileTests(t, []compileTest{
+		{"operators", `(defn f [a b] (fmt/sprint (+ a b) (- a) (* a b 2) (/ a b) (/ a)))`, ""},
+		{"integer division", `(defn f [a b] (fmt/sprint (quot a b) (rem a b) (mod a b)))`, ""},
+		{"conversion", `(defn f [x] (+ (int x) (float64 x)))`, ""},
+		{"literal conversion", `(defn f [] (fmt/sprint (int 1) (rune \a) (float32 2)))`, ""},
+		{"typed conversion", `(defn f [s ^string] (len (fmt/sprint (int64 (len s)))))`, ""},
+		{"loop changing kind", `(defn f [n] (loop [[acc 0] [i 0]] (if (< i n) (recur (+ acc 0.5) (+ i 1)) acc)))`, ""},
+		{"loop to big", `(defn f [] (loop [[n 1]] (if (< n 100) (recur (* n 1N)) n)))`, ""},
+		{"loop accumulator", `(defn f [n] (loop [[acc 0] [i 0]] (if (< i n) (recur (+ acc i) (+ i 1)) acc)))`, ""},
+	})
+}
+
+func TestComparison(t *testing.T) {
+	runCompileTests(t, []compileTest{
+		{"equality", `(defn f [a b] (fmt/sprint (= a b) (= a b 1)))`, ""},
+		{"ordering", `(defn f [a b] (fmt/sprint (< a b 3) (>= a b) (compare a b)))`, ""},
+		{"in conditions", `(defn f [a b] (if (<= a b) a b))`, ""},
+	})
+}
+
+func TestLoopBindingsAreAny(t *testing.T) {
+	// recur may change the kind of number a loop name holds, 0 may
+	// become 0.5, so the names mustn't take the type of their start
+	goSrc, err := generate(`(defn f [n] (loop [[acc 0]] (if (< acc n) (recur (+ acc 0.5)) acc)))`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(goSrc, "var acc core.Any = 0") || strings.Contains(goSrc, "core.Store") {
+		t.Errorf("acc isn't a core.Any:\n%s", goSrc)
+	}
+}
@@ -0,0 +1,24 @@
+package generator
+
+import (
+	"github.com/jcla1/gisp/parser"
+	h "github.com/jcla1/gisp/generator/helpers"
+	"go/ast"
+)
+
+func checkNewArgs(node *parser.CallNode) bool {
+	if !isCallTo(node, "new") {
+		return false
+	}
+
+	if len(node.Args) != 1 {
+		panic("new needs exactly one type!")
+	}
+
+	return true
+}
+
+// (new T) allocates a zeroed T and returns a pointer to it
+func makeNew(node *parser.CallNode) *ast.CallExpr {
+	return makeFuncCall(ast.NewIdent("new"), h.E(evalType(node.Args[0])))
+}
@@ -0,0 +1,13 @@
+package generator
+
+import "testing"
+
+func TestPointers(t *testing.T) {
+	runCompileTests(t, []compileTest{
+		{"new and set!", `(def f (fn [v] (let [[p (new int)]] (set! (* p) v) (* p))))`, ""},
+		{"address-of", `(def f (fn [x] (let [[p (& x)]] (set! (* p) 2) x)))`, ""},
+		{"pointer type", `(def f (fn [] (let [[n 1]] (vec (* int) (& n) nil))))`, ""},
+		{"multiply", `(def f (fn [a b] (* a b 2)))`, ""},
+		{"new args", `(def f (fn [] (new int string)))`, "new needs exactly one type"},
+	})
+}
@@ -0,0 +1,375 @@
+package generator
+
+import (
+	"github.com/jcla1/gisp/parser"
+	h "github.com/jcla1/gisp/generator/helpers"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+)
+
+type protocol struct {
+	name     string
+	methods  []*protocolMethod
+	adapters []*adapter
+}
+
+type protocolMethod struct {
+	name   string
+	params []string
+}
+
+// An adapter is the named type implementing a protocol for a type
+// which we can't declare methods on, like string or
+// *core.PersistentVector.
+type adapter struct {
+	typ  ast.Expr
+	name string
+}
+
+// The protocols declared in this namespace, by name.
+var protocols map[string]*protocol
+
+// registerProtocol records the methods of a defprotocol, returning
+// the names it declares:
+//
+//	(defprotocol Shape
+//	  (area [s])
+//	  (scale [s factor]))
+func registerProtocol(node *parser.CallNode) []string {
+	if len(node.Args) < 2 || node.Args[0].Type() != parser.NodeIdent {
+		panic("defprotocol should look like: (defprotocol Name (method [this args...]) ...)")
+	}
+
+	p := &protocol{name: node.Args[0].(*parser.IdentNode).Ident}
+	declared := []string{p.name, "as-" + p.name}
+
+	for _, m := range node.Args[1:] {
+		sig, ok := m.(*parser.CallNode)
+		if !ok || sig.Callee.Type() != parser.NodeIdent || len(sig.Args) != 1 || sig.Args[0].Type() != parser.NodeVector {
+			panic("invalid protocol method: " + m.String())
+		}
+
+		method := &protocolMethod{name: sig.Callee.(*parser.IdentNode).Ident}
+		for _, param := range sig.Args[0].(*parser.VectorNode).Nodes {
+			ident, ok := param.(*parser.IdentNode)
+			if !ok || ident.Ident == "&" {
+				panic("protocol methods take a fixed number of named parameters: " + m.String())
+			}
+			method.params = append(method.params, ident.Ident)
+		}
+
+		if len(method.params) == 0 {
+			panic("protocol methods need at least a parameter for the value: " + m.String())
+		}
+
+		p.methods = append(p.methods, method)
+		declared = append(declared, method.name)
+	}
+
+	protocols[p.name] = p
+	return declared
+}
+
+// registerAdapters records the adapters of the extend-types in the
+// namespace, since the conversion to a protocol has to know them.
+func registerAdapters(tree []parser.Node) []string {
+	declared := []string{}
+
+	for _, node := range tree {
+		call, ok := node.(*parser.CallNode)
+		if !ok || !isCallTo(call, "extend-type") {
+			continue
+		}
+
+		checkExtendTypeArgs(call)
+		if isLocalType(call.Args[0]) {
+			continue
+		}
+
+		typ := evalType(call.Args[0])
+		for _, name := range extendedProtocols(call) {
+			p := protocols[name.Ident]
+			if p == nil {
+				panic("can only extend " + call.Args[0].String() + " to protocols of this namespace, not " + name.Ident)
+			}
+
+			a := &adapter{typ: typ, name: adapterName(typ, p.name)}
+			p.adapters = append(p.adapters, a)
+			declared = append(declared, a.name)
+		}
+	}
+
+	return declared
+}
+
+// Adapters are named after the type and protocol, i.e. []core.Any
+// adapted to Shape is sliceCoreAnyAsShape.
+func adapterName(typ ast.Expr, protocol string) string {
+	name := types.ExprString(typ)
+	name = strings.NewReplacer("[]", "slice-", "map[", "map-", "*", "ptr-", ".", "-").Replace(name)
+	return name + "-as-" + protocol
+}
+
+// Methods can't be declared on pointer types, so those are wrapped
+// in a struct, i.e. *core.PersistentVector adapted to Shape is
+//
+//	type ptrCorePersistentVectorAsShape struct{ value *core.PersistentVector }
+func adapterType(typ ast.Expr) ast.Expr {
+	if _, ok := typ.(*ast.StarExpr); ok {
+		return &ast.StructType{Fields: makeFieldList([]*ast.Field{makeField(h.I(ast.NewIdent("value")), typ)})}
+	}
+
+	return typ
+}
+
+func wrapAdapter(name *ast.Ident, typ, x ast.Expr) ast.Expr {
+	if _, ok := typ.(*ast.StarExpr); ok {
+		return makeCompositeLit(name, h.E(x))
+	}
+
+	return makeFuncCall(name, h.E(x))
+}
+
+func unwrapAdapter(typ ast.Expr, recv *ast.Ident) ast.Expr {
+	if _, ok := typ.(*ast.StarExpr); ok {
+		return makeSelectorExpr(recv, ast.NewIdent("value"))
+	}
+
+	return makeFuncCall(typ, h.E(recv))
+}
+
+func checkExtendTypeArgs(node *parser.CallNode) {
+	if len(node.Args) < 3 || node.Args[1].Type() != parser.NodeIdent {
+		panic("extend-type should look like: (extend-type T Protocol (method [this args...] body) ...)")
+	}
+
+	for _, arg := range node.Args[1:] {
+		if arg.Type() != parser.NodeIdent && !isMethodImpl(arg) {
+			panic("invalid method implementation: " + arg.String())
+		}
+	}
+
+	if unions[identName(node.Args[0])] != nil {
+		panic("can't extend the union " + identName(node.Args[0]) + ", extend its variants instead")
+	}
+}
+
+func isMethodImpl(node parser.Node) bool {
+	call, ok := node.(*parser.CallNode)
+	return ok && call.Callee.Type() == parser.NodeIdent && len(call.Args) > 0 && call.Args[0].Type() == parser.NodeVector
+}
+
+func identName(node parser.Node) string {
+	if ident, ok := node.(*parser.IdentNode); ok {
+		return ident.Ident
+	}
+	return ""
+}
+
+// Types declared in this namespace, i.e. by defstruct or defunion,
+// get the protocol's methods themselves.
+func isLocalType(node parser.Node) bool {
+	_, ok := packageDefs["->"+identName(node)]
+	return ok
+}
+
+func extendedProtocols(node *parser.CallNode) []*parser.IdentNode {
+	names := []*parser.IdentNode{}
+	for _, arg := range node.Args[1:] {
+		if ident, ok := arg.(*parser.IdentNode); ok {
+			names = append(names, ident)
+		}
+	}
+	return names
+}
+
+// evalDefprotocol declares the protocol's interface, a function
+// converting a value to it and a function for every method:
+//
+//	(defprotocol Shape (area [s]))
+//
+//	type Shape interface {
+//		Area() core.Any
+//	}
+//
+//	func asShape(x core.Any) Shape {
+//		switch x := x.(type) {
+//		case Shape:
+//			return x
+//		case string:
+//			return stringAsShape(x)
+//		}
+//		panic(core.NoImplError{Protocol: "Shape", Value: x})
+//	}
+//
+//	func area(s core.Any) core.Any {
+//		return asShape(s).Area()
+//	}
+func evalDefprotocol(node *parser.CallNode) []ast.Decl {
+	p := protocols[node.Args[0].(*parser.IdentNode).Ident]
+	name := makeIdomaticIdent(p.name)
+	as := makeIdomaticIdent("as-" + p.name)
+
+	methods := []*ast.Field{}
+	funcs := []ast.Decl{}
+
+	for _, m := range p.methods {
+		params := []*ast.Ident{}
+		for _, param := range m.params {
+			params = append(params, makeLocalIdent(param))
+		}
+		methodName := ast.NewIdent(Mangle(m.name, true))
+		results := makeFieldList([]*ast.Field{makeField(nil, anyType)})
+
+		methodType := makeFuncType(results, makeFieldList(makeParamFields(params[1:], make([]ast.Expr, len(params)-1))))
+		methods = append(methods, makeField(h.I(methodName), methodType))
+
+		call := makeFuncCall(makeSelectorExpr(makeFuncCall(as, h.E(params[0])), methodName), identsToExprs(params[1:]))
+		fnType := makeFuncType(results, makeFieldList(makeParamFields(params, make([]ast.Expr, len(params)))))
+		funcs = append(funcs, makeFunDeclFromFuncLit(makeIdomaticIdent(m.name), makeFuncLit(fnType, makeBlockStmt(h.S(makeReturnStmt(h.E(call)))))))
+	}
+
+	x := ast.NewIdent("x")
+	clauses := h.S(&ast.CaseClause{List: h.E(name), Body: h.S(makeReturnStmt(h.E(x)))})
+	for _, a := range p.adapters {
+		conversion := wrapAdapter(makeIdomaticIdent(a.name), a.typ, x)
+		clauses = append(clauses, &ast.CaseClause{List: h.E(a.typ), Body: h.S(makeReturnStmt(h.E(conversion)))})
+	}
+
+	noImpl := makeCompositeLit(makeSelectorExpr(ast.NewIdent("core"), ast.NewIdent("NoImplError")), h.E(
+		makeKeyValueExpr(ast.NewIdent("Protocol"), makeStringLit(strconv.Quote(p.name))),
+		makeKeyValueExpr(ast.NewIdent("Value"), x),
+	))
+
+	typeSwitch := &ast.TypeSwitchStmt{
+		Assign: makeAssignStmt(h.E(x), h.E(makeTypeAssertion(x, nil)), token.DEFINE),
+		Body:   makeBlockStmt(clauses),
+	}
+	asBody := makeBlockStmt(h.S(typeSwitch, makeExprStmt(makeFuncCall(ast.NewIdent("panic"), h.E(noImpl)))))
+	asType := makeFuncType(makeFieldList([]*ast.Field{makeField(nil, name)}), makeFieldList([]*ast.Field{makeField(h.I(x), anyType)}))
+
+	iface := &ast.InterfaceType{Methods: makeFieldList(methods)}
+	decls := []ast.Decl{
+		makeGeneralDecl(token.TYPE, []ast.Spec{&ast.TypeSpec{Name: name, Type: iface}}),
+		makeFunDeclFromFuncLit(as, makeFuncLit(asType, asBody)),
+	}
+
+	return append(decls, funcs...)
+}
+
+func identsToExprs(idents []*ast.Ident) []ast.Expr {
+	exprs := make([]ast.Expr, len(idents))
+	for i, ident := range idents {
+		exprs[i] = ident
+	}
+	return exprs
+}
+
+// evalExtendType declares the methods of the protocols, either on a
+// type of this namespace or on an adapter for the type:
+//
+//	(extend-type string Shape
+//	  (area [s] (len s)))
+//
+//	type stringAsShape string
+//
+//	func (GEN0 stringAsShape) Area() core.Any {
+//		s := string(GEN0)
+//		return len(s)
+//	}
+func evalExtendType(node *parser.CallNode) []ast.Decl {
+	local := isLocalType(node.Args[0])
+	typ := evalType(node.Args[0])
+	decls := []ast.Decl{}
+
+	var p *protocol
+	var recvType ast.Expr
+	implemented := map[string]bool{}
+
+	checkImplemented := func() {
+		if p == nil {
+			return
+		}
+
+		for _, m := range p.methods {
+			if !implemented[m.name] {
+				panic("extend-type " + node.Args[0].String() + " " + p.name + " is missing: " + m.name)
+			}
+		}
+	}
+
+	for _, arg := range node.Args[1:] {
+		if ident, ok := arg.(*parser.IdentNode); ok {
+			checkImplemented()
+			p, recvType, implemented = protocols[ident.Ident], typ, map[string]bool{}
+
+			if !local {
+				recvType = makeIdomaticIdent(adapterName(typ, ident.Ident))
+				spec := &ast.TypeSpec{Name: recvType.(*ast.Ident), Type: adapterType(typ)}
+				decls = append(decls, makeGeneralDecl(token.TYPE, []ast.Spec{spec}))
+			}
+			continue
+		}
+
+		impl := arg.(*parser.CallNode)
+		method := impl.Callee.(*parser.IdentNode).Ident
+		if p != nil {
+			checkProtocolMethod(p, method, impl)
+		}
+		implemented[method] = true
+
+		decls = append(decls, makeMethodDecl(impl, recvType, typ, local))
+	}
+	checkImplemented()
+
+	return decls
+}
+
+func checkProtocolMethod(p *protocol, name string, impl *parser.CallNode) {
+	for _, m := range p.methods {
+		if m.name == name {
+			if len(impl.Args[0].(*parser.VectorNode).Nodes) != len(m.params) {
+				panic("wrong number of parameters for " + p.name + "'s " + name + ": " + impl.String())
+			}
+			return
+		}
+	}
+
+	panic(name + " isn't a method of " + p.name)
+}
+
+// makeMethodDecl generates the method from (name [this args...] body),
+// this being the value of the original type within the body.
+func makeMethodDecl(impl *parser.CallNode, recvType, typ ast.Expr, local bool) *ast.FuncDecl {
+	vect := impl.Args[0].(*parser.VectorNode)
+	this, ok := vect.Nodes[0].(*parser.IdentNode)
+	if !ok {
+		panic("the first parameter of a method has to be an identifier: " + impl.String())
+	}
+
+	fn := &parser.CallNode{
+		NodeType: parser.NodeCall,
+		Callee:   parser.NewIdentNode("fn"),
+		Args:     append([]parser.Node{&parser.VectorNode{NodeType: parser.NodeVector, Nodes: vect.Nodes[1:]}}, impl.Args[1:]...),
+	}
+
+	pushLocals([]string{this.Ident})
+	lit := makeFunc(fn, "")
+	popLocals()
+
+	recv := makeLocalIdent(this.Ident)
+	if !local {
+		recv = generateIdent()
+		if searchForIdent(impl.Args[1:], this.Ident) {
+			unwrap := makeAssignStmt(h.E(makeLocalIdent(this.Ident)), h.E(unwrapAdapter(typ, recv)), token.DEFINE)
+			lit.Body.List = append(h.S(unwrap), lit.Body.List...)
+		}
+	}
+
+	decl := makeFunDeclFromFuncLit(ast.NewIdent(Mangle(impl.Callee.(*parser.IdentNode).Ident, true)), lit)
+	decl.Recv = makeFieldList([]*ast.Field{makeField(h.I(recv), recvType)})
+	return decl
+}
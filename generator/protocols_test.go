@@ -0,0 +1,54 @@
+package generator
+
+import "testing"
+
+const shapeProtocol = `(defprotocol Shape (area [s]) (scale [s factor]))
+(defstruct Square [side])
+`
+
+func TestProtocols(t *testing.T) {
+	runCompileTests(t, []compileTest{
+		{"defprotocol", shapeProtocol + `(defn f [s] (area s))`, ""},
+		{"extend-type", shapeProtocol + `(extend-type Square Shape
+  (area [sq] (* sq/side sq/side))
+  (scale [sq factor] (->Square (* sq/side factor))))
+(defn f [] (area (scale (->Square 2) 3)))`, ""},
+		{"adapter", shapeProtocol + `(extend-type string Shape
+  (area [s] (len s))
+  (scale [s factor] (strings/repeat s (assert int factor))))
+(extend-type ^[]core.Any Shape
+  (area [xs] (len xs))
+  (scale [xs factor] xs))
+(defn f [] (fmt/sprint (area "abc") (area [1 2])))`, ""},
+		{"union variants", `(defprotocol Named (name-of [x]))
+(defunion Animal (Dog name) Cat)
+(extend-type Dog Named (name-of [d] d/name))
+(extend-type Cat Named (name-of [c] "cat"))`, ""},
+		{"bad defprotocol", `(defprotocol Shape)`, "defprotocol should look like"},
+		{"bad method", `(defprotocol Shape area)`, "invalid protocol method"},
+		{"no params", `(defprotocol Shape (area []))`, "at least a parameter for the value"},
+		{"variadic method", `(defprotocol Shape (area [s & more]))`, "fixed number of named parameters"},
+		{"missing method", shapeProtocol + `(extend-type Square Shape (area [sq] 1))`, "extend-type Square Shape is missing: scale"},
+		{"unknown method", shapeProtocol + `(extend-type Square Shape (area [sq] 1) (scale [sq f] sq) (perimeter [sq] 4))`, "perimeter isn't a method of Shape"},
+		{"wrong params", shapeProtocol + `(extend-type Square Shape (area [sq x] 1) (scale [sq f] sq))`, "wrong number of parameters for Shape's area"},
+		{"foreign protocol", `(extend-type string fmt/Stringer (string [s] s))`, "protocols of this namespace"},
+		{"union", `(defprotocol Named (name-of [x]))
+(defunion Animal (Dog name) Cat)
+(extend-type Animal Named (name-of [a] "animal"))`, "can't extend the union Animal"},
+		{"bad extend-type", `(extend-type string)`, "extend-type should look like"},
+	})
+}
+
+func TestMultimethods(t *testing.T) {
+	runCompileTests(t, []compileTest{
+		{"keyword dispatch", `(defmulti speak :kind)
+(defmethod speak :dog [a] "woof")
+(defmethod speak :default [a] "...")
+(defn f [] (speak {:kind :dog}))`, ""},
+		{"fn dispatch", `(defmulti describe (fn [x y] (+ x y)))
+(defmethod describe 2 [x y] "two")`, ""},
+		{"bad defmulti", `(defmulti speak)`, "defmulti should look like"},
+		{"bad defmethod", `(defmulti speak :kind)
+(defmethod speak :dog)`, "defmethod should look like"},
+	})
+}
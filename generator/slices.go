@@ -0,0 +1,123 @@
+package generator
+
+import (
+	"github.com/jcla1/gisp/parser"
+	h "github.com/jcla1/gisp/generator/helpers"
+	"go/ast"
+	"go/token"
+)
+
+// The forms working on typed slices and arrays, with the number
+// of arguments they need at least:
+//
+//	(vec int 1 2 3)          []int{1, 2, 3}
+//	(array int 1 2)          [...]int{1, 2}
+//	(array 3 int)            [3]int{}
+//	(make (slice int) 0 10)  make([]int, 0, 10)
+//	(subvec xs 1 nil)        xs[1:]
+//	(aget xs 0)              xs[0]
+//	(aset xs 0 v)            core.Store(&xs[0], v)
+var sliceForms = map[string]int{
+	"vec":    1,
+	"array":  1,
+	"make":   1,
+	"subvec": 2,
+	"aget":   2,
+	"aset":   3,
+}
+
+func checkSliceArgs(node *parser.CallNode) bool {
+	if node.Callee.Type() != parser.NodeIdent {
+		return false
+	}
+
+	name := node.Callee.(*parser.IdentNode).Ident
+	min, ok := sliceForms[name]
+	if !ok {
+		return false
+	}
+
+	if len(node.Args) < min {
+		panic(name + " is missing arguments!")
+	}
+
+	switch {
+	case name == "subvec" && (len(node.Args) < 2 || len(node.Args) > 4):
+		panic("subvec needs a slice and one to three bounds!")
+	case name == "aget" && len(node.Args) != 2:
+		panic("aget needs a slice and an index!")
+	case name == "aset" && len(node.Args) != 3:
+		panic("aset needs a slice, an index and a value!")
+	}
+
+	return true
+}
+
+func makeSliceForm(node *parser.CallNode) ast.Expr {
+	args := node.Args
+
+	switch node.Callee.(*parser.IdentNode).Ident {
+	case "vec":
+		return makeVector(evalType(args[0]), makeTypedExprs(args[1:], args[0]))
+
+	case "array":
+		if args[0].Type() == parser.NodeNumber {
+			if len(args) < 2 {
+				panic("array needs an element type after its length!")
+			}
+			typ := &ast.ArrayType{Len: EvalExpr(args[0]), Elt: evalType(args[1])}
+			return makeCompositeLit(typ, makeTypedExprs(args[2:], args[1]))
+		}
+
+		typ := &ast.ArrayType{Len: &ast.Ellipsis{}, Elt: evalType(args[0])}
+		return makeCompositeLit(typ, makeTypedExprs(args[1:], args[0]))
+
+	case "make":
+		sizes := make([]ast.Expr, 0, len(args))
+		for _, size := range args[1:] {
+			sizes = append(sizes, makeIntExpr(size))
+		}
+		return makeFuncCall(ast.NewIdent("make"), append(h.E(evalType(args[0])), sizes...))
+
+	case "subvec":
+		return makeSliceExpr(EvalExpr(args[0]), args[1:])
+
+	case "aget":
+		return makeIndexExpr(EvalExpr(args[0]), makeIntExpr(args[1]))
+
+	case "aset":
+		elem := makeIndexExpr(EvalExpr(args[0]), makeIntExpr(args[1]))
+		return coreCall("Store", makeUnaryExpr(token.AND, elem), EvalExpr(args[2]))
+	}
+
+	return nil
+}
+
+// A nil bound is left out, (subvec xs nil 2) is xs[:2]
+func makeSliceExpr(x ast.Expr, bounds []parser.Node) *ast.SliceExpr {
+	indices := make([]ast.Expr, 3)
+	for i, bound := range bounds {
+		if bound.Type() != parser.NodeNil {
+			indices[i] = makeIntExpr(bound)
+		}
+	}
+
+	if len(bounds) == 3 && (indices[1] == nil || indices[2] == nil) {
+		panic("subvec needs the high and max bound of a 3-index slice!")
+	}
+
+	return &ast.SliceExpr{
+		X:      x,
+		Low:    indices[0],
+		High:   indices[1],
+		Max:    indices[2],
+		Slice3: len(bounds) == 3,
+	}
+}
+
+func makeIndexExpr(x, index ast.Expr) *ast.IndexExpr {
+	return &ast.IndexExpr{
+		X:     x,
+		Index: index,
+	}
+}
@@ -0,0 +1,25 @@
+package generator
+
+import "testing"
+
+func TestSliceForms(t *testing.T) {
+	runCompileTests(t, []compileTest{
+		{"vec", `(def f (fn [x] (vec int 1 2 x)))`, ""},
+		{"vec of strings", `(def xs (vec string "a" "b"))`, ""},
+		{"array", `(def f (fn [] (array int 1 2)))`, ""},
+		{"sized array", `(def f (fn [] (array 3 int)))`, ""},
+		{"make", `(def f (fn [n] (make (slice int) 0 n)))`, ""},
+		{"make map", `(def f (fn [] (make (map string int))))`, ""},
+		{"make chan", `(def f (fn [] (make (chan int) 1)))`, ""},
+		{"subvec", `(def f (fn [n] (let [[xs (vec int 1 2 3)]] (fmt/sprint (subvec xs 1 nil) (subvec xs nil n) (subvec xs 0 1 2)))))`, ""},
+		{"aget", `(def f (fn [i] (let [[xs (vec string "a" "b")]] (aget xs i))))`, ""},
+		{"aset", `(def f (fn [v] (let [[xs (make (slice int) 2)]] (aset xs 0 v))))`, ""},
+		{"len and append", `(def f (fn [] (let [[xs (vec int 1)]] (len (append xs 2)))))`, ""},
+		{"missing args", `(def f (fn [] (aset)))`, "aset is missing arguments"},
+		{"too many bounds", `(def f (fn [xs] (subvec xs 1 2 3 4)))`, "one to three bounds"},
+		{"3-index nil", `(def f (fn [xs] (subvec xs 1 nil 2)))`, "high and max bound"},
+		{"aget args", `(def f (fn [xs] (aget xs 1 2)))`, "aget needs a slice and an index"},
+		{"array without type", `(def f (fn [] (array 3)))`, "element type after its length"},
+		{"not a type", `(def f (fn [] (vec "int" 1)))`, "not a type"},
+	})
+}
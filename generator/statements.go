@@ -59,3 +59,40 @@ func makeForStmt(init, post ast.Stmt, cond ast.Expr, body *ast.BlockStmt) *ast.F
 		Body: body,
 	}
 }
+
+func makeDeferStmt(call *ast.CallExpr) *ast.DeferStmt {
+	return &ast.DeferStmt{
+		Call: call,
+	}
+}
+
+func makeSwitchStmt(tag ast.Expr, body *ast.BlockStmt) *ast.SwitchStmt {
+	return &ast.SwitchStmt{
+		Tag:  tag,
+		Body: body,
+	}
+}
+
+func makeCaseClause(list []ast.Expr, body []ast.Stmt) *ast.CaseClause {
+	return &ast.CaseClause{
+		List: list,
+		Body: body,
+	}
+}
+
+func makeRangeStmt(key, value, x ast.Expr, body *ast.BlockStmt) *ast.RangeStmt {
+	return &ast.RangeStmt{
+		Key:   key,
+		Value: value,
+		Tok:   token.DEFINE,
+		X:     x,
+		Body:  body,
+	}
+}
+
+func makeIncDecStmt(x ast.Expr, tok token.Token) *ast.IncDecStmt {
+	return &ast.IncDecStmt{
+		X:   x,
+		Tok: tok,
+	}
+}
@@ -0,0 +1,85 @@
+package generator
+
+import (
+	"github.com/jcla1/gisp/parser"
+	h "github.com/jcla1/gisp/generator/helpers"
+	"go/ast"
+	"go/token"
+)
+
+// evalDefstruct declares a struct with exported fields, so they
+// can be reached with p/first, and its constructor ->Pair taking
+// the fields in order:
+//
+//	(defstruct [T any] Pair [first ^T second])
+//
+//	type Pair[T any] struct {
+//		First  T
+//		Second core.Any
+//	}
+//
+//	func to_Pair[T any](first T, second core.Any) Pair[T] {
+//		return Pair[T]{First: first, Second: second}
+//	}
+func evalDefstruct(node *parser.CallNode) []ast.Decl {
+	args := node.Args
+	var typeParams *ast.FieldList
+	if len(args) > 0 && args[0].Type() == parser.NodeVector {
+		typeParams = makeTypeParams(args[0].(*parser.VectorNode))
+		args = args[1:]
+	}
+
+	if len(args) != 2 || args[0].Type() != parser.NodeIdent || args[1].Type() != parser.NodeVector {
+		panic("defstruct should look like: (defstruct Name [field ^T ...]) or (defstruct [T constraint] Name [...])")
+	}
+
+	name := args[0].(*parser.IdentNode).Ident
+	fieldNodes, types := splitTypeHints(args[1].(*parser.VectorNode).Nodes)
+
+	fieldNames := []string{}
+	for _, field := range fieldNodes {
+		ident, ok := field.(*parser.IdentNode)
+		if !ok || ident.Ident == "&" {
+			panic("defstruct fields have to be identifiers: " + field.String())
+		}
+		fieldNames = append(fieldNames, ident.Ident)
+	}
+	checkNameCollisions(fieldNames)
+
+	fields := []*ast.Field{}
+	params := []*ast.Ident{}
+	elts := h.EmptyE()
+
+	for i, field := range fieldNames {
+		typ := types[i]
+		if typ == nil {
+			typ = anyType
+		}
+
+		exported := ast.NewIdent(Mangle(field, true))
+		param := makeLocalIdent(field)
+
+		fields = append(fields, makeField(h.I(exported), typ))
+		params = append(params, param)
+		elts = append(elts, makeKeyValueExpr(exported, param))
+	}
+
+	typeName := makeIdomaticIdent(name)
+	typeSpec := &ast.TypeSpec{
+		Name:       typeName,
+		TypeParams: typeParams,
+		Type:       &ast.StructType{Fields: makeFieldList(fields)},
+	}
+
+	var instance ast.Expr = typeName
+	if typeParams != nil {
+		instance = makeInstantiation(typeName, makeTypeArgs(typeParams))
+	}
+
+	body := makeBlockStmt(h.S(makeReturnStmt(h.E(makeCompositeLit(instance, elts)))))
+	results := makeFieldList([]*ast.Field{makeField(nil, instance)})
+	constructor := makeFunDeclFromFuncLit(makeIdomaticIdent("->"+name), makeFuncLit(makeFuncType(results, makeFieldList(makeParamFields(params, types))), body))
+	constructor.Type.TypeParams = typeParams
+
+	return []ast.Decl{makeGeneralDecl(token.TYPE, []ast.Spec{typeSpec}), constructor}
+}
@@ -0,0 +1,332 @@
+package generator
+
+import (
+	"github.com/jcla1/gisp/parser"
+	h "github.com/jcla1/gisp/generator/helpers"
+	"go/ast"
+	"go/token"
+)
+
+// tailTarget decides which calls in tail position are jumps
+// and generates the statements making those jumps.
+type tailTarget interface {
+	isTailCall(node *parser.CallNode) bool
+	makeTailCall(node *parser.CallNode) []ast.Stmt
+}
+
+// selfTarget is a function that recur, or a call
+// to the function's own name, jumps back to.
+type selfTarget struct {
+	self     string
+	params   []*ast.Ident
+	ellipsis *ast.Ident
+	// of typed fns, nil being core.Any, the rest parameter's last
+	types []ast.Expr
+	// the variables tail calls assign the parameters' next values
+	// to, the rest parameter's last
+	vars []*ast.Ident
+}
+
+func (t *selfTarget) isTailCall(node *parser.CallNode) bool {
+	ident, ok := node.Callee.(*parser.IdentNode)
+	if !ok {
+		return false
+	}
+
+	if ident.Ident == "recur" {
+		return true
+	}
+
+	if t.self == "" || ident.Ident != t.self {
+		return false
+	}
+
+	// A call to another arity isn't a jump
+	if len(node.Args) < len(t.params) || (t.ellipsis == nil && len(node.Args) != len(t.params)) {
+		return false
+	}
+
+	// A parameter shadows the function's name
+	name := makeLocalIdent(t.self).Name
+	for _, param := range t.params {
+		if param.Name == name {
+			return false
+		}
+	}
+
+	return t.ellipsis == nil || t.ellipsis.Name != name
+}
+
+// hasTailCall reports whether any of the tail positions of the
+// body either recurs or calls the function itself.
+func hasTailCall(body []parser.Node, target tailTarget) bool {
+	found := false
+	eachTailCall(body, func(node *parser.CallNode) {
+		found = found || target.isTailCall(node)
+	})
+	return found
+}
+
+// eachTailCall calls visit for every call in tail position of the
+// body, looking through the control flow forms.
+func eachTailCall(body []parser.Node, visit func(*parser.CallNode)) {
+	if len(body) == 0 {
+		return
+	}
+
+	node, ok := body[len(body)-1].(*parser.CallNode)
+	if !ok {
+		return
+	}
+
+	switch {
+	case checkIfArgs(node):
+		eachTailCall(node.Args[1:2], visit)
+		eachTailCall(node.Args[2:], visit)
+
+	case checkWhenArgs(node):
+		eachTailCall(node.Args[1:], visit)
+
+	case checkCondArgs(node):
+		for i := 1; i < len(node.Args); i += 2 {
+			eachTailCall(node.Args[i:i+1], visit)
+		}
+
+	case checkCaseArgs(node), checkTypeCaseArgs(node), checkMatchArgs(node):
+		for i := 2; i < len(node.Args); i += 2 {
+			eachTailCall(node.Args[i:i+1], visit)
+		}
+
+	case checkDoArgs(node):
+		eachTailCall(node.Args, visit)
+
+	case checkLetArgs(node):
+		eachTailCall(node.Args[1:], visit)
+
+	default:
+		visit(node)
+	}
+}
+
+// makeTailLoopBody wraps the function body into an endless for
+// loop. Tail calls reassign the parameters and continue it, so the
+// prelude (i.e. destructuring the parameters) runs on every pass.
+func makeTailLoopBody(prelude []ast.Stmt, body []parser.Node, target tailTarget) *ast.BlockStmt {
+	forBody := makeBlockStmt(append(prelude, evalTailBody(body, target)...))
+	return makeBlockStmt(h.S(makeForStmt(nil, nil, nil, forBody)))
+}
+
+// makeSelfTailLoop generates the body of a fn calling itself in
+// tail position. Its parameters are bound anew on every pass, from
+// the variables the tail calls assign, so that closures made in an
+// earlier pass keep seeing the values of that pass:
+//
+//	GEN0, GEN1 := n, acc
+//	for {
+//		n, acc := GEN0, GEN1
+//		...
+//		GEN0, GEN1 = n - 1, acc * n
+//		continue
+//	}
+func makeSelfTailLoop(prelude []ast.Stmt, body []parser.Node, t *selfTarget) *ast.BlockStmt {
+	params := t.params
+	if t.ellipsis != nil {
+		params = append(params[:len(params):len(params)], t.ellipsis)
+	}
+
+	t.vars = make([]*ast.Ident, len(params))
+	for i := range params {
+		t.vars[i] = generateIdent()
+	}
+
+	block := makeTailLoopBody(prelude, body, t)
+	loop := block.List[0].(*ast.ForStmt)
+
+	var inner, vars, outer []ast.Expr
+	for i, param := range params {
+		if !refersTo(loop.Body, param.Name) {
+			// nothing can see the parameter, it's simply reassigned
+			t.vars[i].Name = param.Name
+			continue
+		}
+
+		inner = append(inner, ast.NewIdent(param.Name))
+		vars = append(vars, t.vars[i])
+		outer = append(outer, param)
+	}
+
+	if len(inner) == 0 {
+		return block
+	}
+
+	loop.Body.List = append(h.S(makeAssignStmt(inner, vars, token.DEFINE)), loop.Body.List...)
+	return makeBlockStmt(h.S(makeAssignStmt(vars, outer, token.DEFINE), loop))
+}
+
+func refersTo(node ast.Node, name string) bool {
+	found := false
+	ast.Inspect(node, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok && ident.Name == name {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+func evalTailBody(body []parser.Node, target tailTarget) []ast.Stmt {
+	if len(body) == 0 {
+		return h.S(makeReturnStmt(h.E(ast.NewIdent("nil"))))
+	}
+
+	stmts := evalStmts(body[:len(body)-1])
+	return append(stmts, evalTail(body[len(body)-1], target)...)
+}
+
+// evalTail generates the statements returning the value of node.
+// Control flow becomes statements, so the tail calls within can
+// continue the loop.
+func evalTail(node parser.Node, target tailTarget) []ast.Stmt {
+	n, ok := node.(*parser.CallNode)
+	if !ok {
+		return h.S(makeReturnStmt(h.E(EvalExpr(node))))
+	}
+
+	switch {
+	case target.isTailCall(n):
+		return target.makeTailCall(n)
+
+	case checkIfArgs(n):
+		elseBody := evalTailBody(n.Args[2:], target)
+		return h.S(makeIfStmt(EvalExpr(n.Args[0]), makeBlockStmt(evalTail(n.Args[1], target)), makeBlockStmt(elseBody)))
+
+	case checkWhenArgs(n):
+		cond := EvalExpr(n.Args[0])
+		if isCallTo(n, "unless") {
+			cond = makeUnaryExpr(token.NOT, cond)
+		}
+
+		ifStmt := makeIfStmt(cond, makeBlockStmt(evalTailBody(n.Args[1:], target)), nil)
+		return h.S(ifStmt, makeReturnStmt(h.E(ast.NewIdent("nil"))))
+
+	case checkCondArgs(n):
+		return h.S(makeCondTail(n.Args, target))
+
+	case checkCaseArgs(n):
+		return makeCaseTail(n, target)
+
+	case checkTypeCaseArgs(n):
+		return h.S(makeTypeSwitch(n, func(expr parser.Node) []ast.Stmt {
+			return evalTail(expr, target)
+		}))
+
+	case checkMatchArgs(n):
+		return h.S(makeBlockStmt(makeMatch(n, func(expr parser.Node) []ast.Stmt {
+			return evalTail(expr, target)
+		})))
+
+	case checkDoArgs(n):
+		return evalTailBody(n.Args, target)
+
+	case checkLetArgs(n):
+		bindings := makeLetBindings(n)
+		defer popLocals()
+		return h.S(makeBlockStmt(append(bindings, evalTailBody(n.Args[1:], target)...)))
+	}
+
+	return h.S(makeReturnStmt(h.E(EvalExpr(n))))
+}
+
+func makeCondTail(clauses []parser.Node, target tailTarget) ast.Stmt {
+	if len(clauses) == 0 {
+		return makeReturnStmt(h.E(ast.NewIdent("nil")))
+	}
+
+	if isElseKeyword(clauses[0]) {
+		return makeBlockStmt(evalTail(clauses[1], target))
+	}
+
+	otherwise := makeCondTail(clauses[2:], target)
+	if _, ok := otherwise.(*ast.ReturnStmt); ok {
+		otherwise = makeBlockStmt(h.S(otherwise))
+	}
+
+	return makeIfStmt(EvalExpr(clauses[0]), makeBlockStmt(evalTail(clauses[1], target)), otherwise)
+}
+
+func makeCaseTail(node *parser.CallNode, target tailTarget) []ast.Stmt {
+	clauses := h.EmptyS()
+	hasDefault := false
+
+	for i := 1; i < len(node.Args); i += 2 {
+		var list []ast.Expr
+		if isElseKeyword(node.Args[i]) {
+			hasDefault = true
+		} else {
+			list = EvalExprs(getCaseConstants(node.Args[i]))
+		}
+
+		clauses = append(clauses, makeCaseClause(list, evalTail(node.Args[i+1], target)))
+	}
+
+	if !hasDefault {
+		clauses = append(clauses, makeCaseClause(nil, h.S(makeReturnStmt(h.E(ast.NewIdent("nil"))))))
+	}
+
+	return h.S(makeSwitchStmt(EvalExpr(node.Args[0]), makeBlockStmt(clauses)))
+}
+
+// makeTailCall assigns all parameters at once, so that the new
+// values are computed from the old ones, and continues the loop.
+func (t *selfTarget) makeTailCall(node *parser.CallNode) []ast.Stmt {
+	args := EvalExprs(node.Args)
+	lhs := make([]ast.Expr, 0, len(t.params)+1)
+	for i := range t.params {
+		lhs = append(lhs, t.vars[i])
+		if i < len(args) && t.types[i] != nil {
+			args[i] = makeAs(t.types[i], args[i])
+		}
+	}
+
+	var restType ast.Expr = anyType
+	if t.ellipsis != nil && t.types[len(t.params)] != nil {
+		restType = t.types[len(t.params)]
+	}
+
+	switch {
+	case t.ellipsis == nil:
+		if len(args) != len(t.params) {
+			panic("wrong number of arguments in tail call to: " + t.self)
+		}
+
+	// recur passes the rest arguments as a single vector
+	case isCallTo(node, "recur"):
+		if len(args) != len(t.params)+1 {
+			panic("wrong number of arguments to recur!")
+		}
+
+		lhs = append(lhs, t.vars[len(t.params)])
+		args[len(args)-1] = makeAs(&ast.ArrayType{Elt: restType}, args[len(args)-1])
+
+	default:
+		if len(args) < len(t.params) {
+			panic("not enough arguments in tail call to: " + t.self)
+		}
+
+		lhs = append(lhs, t.vars[len(t.params)])
+		// a copy, as appending the vector to args overwrites them
+		elems := append([]ast.Expr(nil), args[len(t.params):]...)
+		if restType != anyType {
+			for i := range elems {
+				elems[i] = makeAs(restType, elems[i])
+			}
+		}
+		args = append(args[:len(t.params)], makeVector(restType, elems))
+	}
+
+	if len(lhs) == 0 {
+		return h.S(makeBranchStmt(token.CONTINUE, nil))
+	}
+
+	return h.S(makeAssignStmt(lhs, args, token.ASSIGN), makeBranchStmt(token.CONTINUE, nil))
+}
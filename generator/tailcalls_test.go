@@ -0,0 +1,37 @@
+package generator
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestTailCalls(t *testing.T) {
+	runCompileTests(t, []compileTest{
+		{"self call", `(def fact (fn [n acc] (if (< n 2) acc (fact (- n 1) (* n acc)))))`, ""},
+		{"recur", `(def count-down (fn [n] (when (> n 0) (recur (- n 1)))))`, ""},
+		{"recur in cond", `(def f (fn [n] (cond (= n 0) "zero" (> n 0) (recur (- n 1)) :else (recur (+ n 1)))))`, ""},
+		{"recur in case", `(def f (fn [n] (case n 0 "zero" :else (recur (- n 1)))))`, ""},
+		{"recur in let", `(def f (fn [n] (let [[m (- n 1)]] (if (> m 0) (recur m) m))))`, ""},
+		{"recur rest", `(def f (fn [a & xs] (if (> a 0) (recur (- a 1) [a]) xs)))`, ""},
+		{"recur same rest", `(def f (fn [a & xs] (if (> a 0) (recur (- a 1) xs) xs)))`, ""},
+		{"self call rest", `(def s (fn [acc & xs] (if (nil? xs) acc (s acc 1 2))))`, ""},
+		{"closure over param", `(def f (fn [n g] (if (> n 0) (recur (- n 1) (fn [] n)) g)))`, ""},
+		{"unused param", `(def f (fn [n m] (if (> n 0) (recur (- n 1) 0) n)))`, ""},
+		{"no params", `(def f (fn [] (recur)))`, ""},
+		{"wrong arity", `(def f (fn [a b] (recur 1)))`, "wrong number of arguments in tail call"},
+		{"wrong recur arity", `(def f (fn [a & xs] (recur 1)))`, "wrong number of arguments to recur"},
+	})
+}
+
+func TestTailCallRebinds(t *testing.T) {
+	// closures made in a pass must see that pass' n, so n is bound
+	// anew inside the loop
+	goSrc, err := generate(`(def f (fn [n g] (if (> n 0) (recur (- n 1) (fn [] n)) g)))`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !regexp.MustCompile(`for \{\s+n, g := GEN\d+, GEN\d+`).MatchString(goSrc) {
+		t.Errorf("parameters aren't rebound in the loop:\n%s", goSrc)
+	}
+}
@@ -0,0 +1,226 @@
+package generator
+
+import (
+	"github.com/jcla1/gisp/parser"
+)
+
+// The threading forms, rewritten into the nested calls they stand
+// for before any code is generated.
+var threadingForms = map[string]func(*parser.CallNode) parser.Node{
+	"->":     func(n *parser.CallNode) parser.Node { return threadAll(n, threadFirst) },
+	"->>":    func(n *parser.CallNode) parser.Node { return threadAll(n, threadLast) },
+	"as->":   expandAsThread,
+	"some->": expandSomeThread,
+	"cond->": expandCondThread,
+}
+
+// ExpandThreading rewrites the threading forms within the nodes, so
+//
+//	(-> x (f 1) g)
+//
+// becomes (g (f x 1)), generating the same code as if it had been
+// written that way.
+func ExpandThreading(nodes []parser.Node) []parser.Node {
+	out := make([]parser.Node, len(nodes))
+	for i, node := range nodes {
+		out[i] = expandThreading(node)
+	}
+	return out
+}
+
+func expandThreading(node parser.Node) parser.Node {
+	switch n := node.(type) {
+	case *parser.CallNode:
+		if ident, ok := n.Callee.(*parser.IdentNode); ok {
+			if expand, ok := threadingForms[ident.Ident]; ok {
+				if len(n.Args) < 1 {
+					panic(ident.Ident + " needs an expression to thread!")
+				}
+				return expandThreading(expand(n))
+			}
+		}
+
+		c := *n
+		c.Callee = expandThreading(n.Callee)
+		c.Args = ExpandThreading(n.Args)
+		return &c
+
+	case *parser.VectorNode:
+		v := *n
+		v.Nodes = ExpandThreading(n.Nodes)
+		return &v
+
+	case *parser.MapNode:
+		m := *n
+		m.Nodes = ExpandThreading(n.Nodes)
+		return &m
+
+	case *parser.SetNode:
+		s := *n
+		s.Nodes = ExpandThreading(n.Nodes)
+		return &s
+	}
+
+	return node
+}
+
+func newCall(callee parser.Node, args ...parser.Node) *parser.CallNode {
+	return &parser.CallNode{NodeType: parser.NodeCall, Callee: callee, Args: args}
+}
+
+func threadAll(node *parser.CallNode, thread func(x, form parser.Node) parser.Node) parser.Node {
+	x := node.Args[0]
+	for _, form := range node.Args[1:] {
+		x = thread(x, form)
+	}
+	return x
+}
+
+// (-> x (f a)) is (f x a) and (-> x f) is (f x)
+func threadFirst(x, form parser.Node) parser.Node {
+	call, ok := form.(*parser.CallNode)
+	if !ok {
+		return newCall(form, x)
+	}
+
+	return newCall(call.Callee, append([]parser.Node{x}, call.Args...)...)
+}
+
+// (->> x (f a)) is (f a x)
+func threadLast(x, form parser.Node) parser.Node {
+	call, ok := form.(*parser.CallNode)
+	if !ok {
+		return newCall(form, x)
+	}
+
+	return newCall(call.Callee, append(append([]parser.Node{}, call.Args...), x)...)
+}
+
+// (as-> x v (f v 1) (g 2 v)) is (g 2 (f x 1)), unless a form uses v
+// more than once, which then binds it with a let instead.
+func expandAsThread(node *parser.CallNode) parser.Node {
+	if len(node.Args) < 2 || node.Args[1].Type() != parser.NodeIdent {
+		panic("as-> should look like: (as-> expr name forms...)")
+	}
+
+	x, name := node.Args[0], node.Args[1].(*parser.IdentNode)
+	for _, form := range node.Args[2:] {
+		switch uses := countIdent(form, name.Ident); {
+		case uses > 1:
+			x = letOne(name, x, form)
+		case uses == 0 && x.Type() == parser.NodeCall:
+			// still evaluate x, for its side effects
+			x = newCall(parser.NewIdentNode("do"), x, form)
+		default:
+			x = replaceIdent(form, name.Ident, x)
+		}
+	}
+
+	return x
+}
+
+// (some-> x f g) threads like ->, but stops at the first nil:
+//
+//	(let [[GEN0 (core/Any x)]] (if (nil? GEN0) nil (some-> (f GEN0) g)))
+func expandSomeThread(node *parser.CallNode) parser.Node {
+	if len(node.Args) == 1 {
+		return node.Args[0]
+	}
+
+	x := parser.NewIdentNode(generateIdent().Name)
+	rest := newCall(node.Callee, append([]parser.Node{threadFirst(x, node.Args[1])}, node.Args[2:]...)...)
+	check := newCall(parser.NewIdentNode("if"), newCall(parser.NewIdentNode("nil?"), x), &parser.NilNode{NodeType: parser.NodeNil}, rest)
+
+	return letOne(x, node.Args[0], check)
+}
+
+// (cond-> x test f) only threads x through the forms whose test is
+// true:
+//
+//	(let [[GEN0 (core/Any x)]] (if test (f GEN0) GEN0))
+func expandCondThread(node *parser.CallNode) parser.Node {
+	if len(node.Args)%2 != 1 {
+		panic("cond-> needs an expression followed by test/form pairs!")
+	}
+
+	if len(node.Args) == 1 {
+		return node.Args[0]
+	}
+
+	x := parser.NewIdentNode(generateIdent().Name)
+	step := newCall(parser.NewIdentNode("if"), node.Args[1], threadFirst(x, node.Args[2]), x)
+	rest := newCall(node.Callee, append([]parser.Node{step}, node.Args[3:]...)...)
+
+	return letOne(x, node.Args[0], rest)
+}
+
+// letOne binds name to value as a core.Any, (core/Any value), since
+// value may be a literal like nil, which Go can't infer a type from.
+func letOne(name *parser.IdentNode, value, body parser.Node) *parser.CallNode {
+	value = newCall(parser.NewIdentNode("core/Any"), value)
+	binding := &parser.VectorNode{NodeType: parser.NodeVector, Nodes: []parser.Node{name, value}}
+	return newCall(parser.NewIdentNode("let"), &parser.VectorNode{NodeType: parser.NodeVector, Nodes: []parser.Node{binding}}, body)
+}
+
+func countIdent(node parser.Node, name string) int {
+	switch n := node.(type) {
+	case *parser.IdentNode:
+		if n.Ident == name {
+			return 1
+		}
+	case *parser.CallNode:
+		return countIdent(n.Callee, name) + countIdents(n.Args, name)
+	case *parser.VectorNode:
+		return countIdents(n.Nodes, name)
+	case *parser.MapNode:
+		return countIdents(n.Nodes, name)
+	case *parser.SetNode:
+		return countIdents(n.Nodes, name)
+	}
+
+	return 0
+}
+
+func countIdents(nodes []parser.Node, name string) int {
+	count := 0
+	for _, node := range nodes {
+		count += countIdent(node, name)
+	}
+	return count
+}
+
+func replaceIdent(node parser.Node, name string, with parser.Node) parser.Node {
+	replaceAll := func(nodes []parser.Node) []parser.Node {
+		out := make([]parser.Node, len(nodes))
+		for i, n := range nodes {
+			out[i] = replaceIdent(n, name, with)
+		}
+		return out
+	}
+
+	switch n := node.(type) {
+	case *parser.IdentNode:
+		if n.Ident == name {
+			return with
+		}
+	case *parser.CallNode:
+		c := *n
+		c.Callee = replaceIdent(n.Callee, name, with)
+		c.Args = replaceAll(n.Args)
+		return &c
+	case *parser.VectorNode:
+		v := *n
+		v.Nodes = replaceAll(n.Nodes)
+		return &v
+	case *parser.MapNode:
+		m := *n
+		m.Nodes = replaceAll(n.Nodes)
+		return &m
+	case *parser.SetNode:
+		s := *n
+		s.Nodes = replaceAll(n.Nodes)
+		return &s
+	}
+
+	return node
+}
@@ -0,0 +1,92 @@
+package generator
+
+import (
+	"github.com/jcla1/gisp/parser"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+var expandTests = []struct {
+	src      string
+	expanded string
+}{
+	{"(-> x (f 1) g)", "[(g (f x 1))]"},
+	{"(->> x (f 1) g)", "[(g (f 1 x))]"},
+	{"(-> x)", "[x]"},
+	{"(as-> x v (f v 1) (g 2 v))", "[(g 2 (f x 1))]"},
+	{"(as-> (h 0) v (f 1) (g v))", "[(g (do (h 0) (f 1)))]"},
+	{"(as-> (h 0) v (f v v))", "[(let [[v (core/Any (h 0))]] (f v v))]"},
+	{"[(-> x f) {:a (->> y g)}]", "[[(f x) {:a (g y)}]]"},
+	{"(f (-> x (g 1)))", "[(f (g x 1))]"},
+}
+
+func TestExpandThreading(t *testing.T) {
+	for _, test := range expandTests {
+		tree := ExpandThreading(parser.ParseFromString("test", test.src))
+		if got := fmt.Sprint(tree); got != test.expanded {
+			t.Errorf("%s expanded to %s, want %s", test.src, got, test.expanded)
+		}
+	}
+}
+
+func TestThreading(t *testing.T) {
+	runCompileTests(t, []compileTest{
+		{"->", `(defn f [x] (-> x (+ 1) (* 2) fmt/sprint))`, ""},
+		{"->>", `(defn f [xs] (->> xs (fmt/sprint "xs:") (strings/to-upper)))`, ""},
+		{"as->", `(defn f [x] (as-> x v (+ v 1) (* v v)))`, ""},
+		{"some->", `(defn f [x] (some-> x (+ 1) fmt/sprint))`, ""},
+		{"cond->", `(defn f [x] (cond-> x (> x 1) (+ 1) (< x 10) (* 2)))`, ""},
+		{"nothing to thread", `(defn f [] (->))`, "needs an expression to thread"},
+		{"some-> nil", `(def inc (fn [x] (+ x 1)))
+(defn f [] (some-> nil inc))`, ""},
+		{"cond-> literal", `(defn f [] (cond-> 1 true (+ 1)))`, ""},
+		{"as-> nil", `(defn f [] (as-> nil v (fmt/sprint v v)))`, ""},
+		{"bad as->", `(defn f [x] (as-> x 1 (+ 1)))`, "as-> should look like"},
+		{"odd cond->", `(defn f [x] (cond-> x (> x 1)))`, "test/form pairs"},
+	})
+}
+
+func TestFnHelpers(t *testing.T) {
+	runCompileTests(t, []compileTest{
+		{"comp", `(defn inc [x] (+ x 1))
+(defn f [] ((comp inc inc) 1))`, ""},
+		{"partial", `(defn add [a b] (+ a b))
+(defn f [] ((partial add 1) 2))`, ""},
+		{"juxt", `(defn inc [x] (+ x 1))
+(defn f [] ((juxt inc fmt/sprint) 1))`, ""},
+		{"complement", `(defn small? [x] (< x 10))
+(defn f [] ((complement small?) 1))`, ""},
+		{"nil?", `(defn f [x] (if (nil? x) 0 x))`, ""},
+		{"core fn as value", `(defn f [] ((complement nil?) 1))`, ""},
+		{"operator as value", `(defn f [] ((partial + 10) 5))`, ""},
+		{"comparison as value", `(defn f [] ((juxt < = >=) 1 2))`, ""},
+		{"core fns composed", `(defn f [xs] ((comp count vector) xs (partial get :a)))`, ""},
+		{"shadowed by a local", `(defn f [count] (+ count 1))`, ""},
+		{"shadowed by a def", `(def rem 3)
+(defn f [] (+ rem 1))`, ""},
+	})
+}
+
+func TestCoreValues(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`(defn f [] ((complement nil?) 1))`, "core.Complement(core.IsNil)"},
+		{`(defn f [] ((partial + 10) 5))`, "core.Partial(core.ADD, 10)"},
+		{`(defn f [xs] (fmt/sprint (apply + 1 xs) mod))`, "core.Apply(core.ADD, 1, xs), core.MOD"},
+		{`(defn f [count] count)`, "return count"},
+	}
+
+	for _, test := range tests {
+		goSrc, err := generate(test.src)
+		if err != nil {
+			t.Errorf("%s: %v", test.src, err)
+			continue
+		}
+		if !strings.Contains(goSrc, test.want) {
+			t.Errorf("%s: %q is missing in\n%s", test.src, test.want, goSrc)
+		}
+	}
+}
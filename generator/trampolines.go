@@ -0,0 +1,323 @@
+package generator
+
+import (
+	"github.com/jcla1/gisp/parser"
+	h "github.com/jcla1/gisp/generator/helpers"
+	"go/ast"
+	"go/token"
+	"strconv"
+)
+
+// tailGroup is a set of top-level fns that call each other in tail
+// position. They get compiled into a single dispatch loop, so that
+// they run in constant stack space.
+type tailGroup struct {
+	dispatch *ast.Ident
+	fnIdx    *ast.Ident
+	args     *ast.Ident
+	names    []string
+	fns      []*parser.CallNode
+	index    map[string]int
+}
+
+// groupTarget jumps to any function of the group, current being
+// the index of the function whose body is being generated.
+type groupTarget struct {
+	group   *tailGroup
+	current int
+}
+
+func (t *groupTarget) isTailCall(node *parser.CallNode) bool {
+	ident, ok := node.Callee.(*parser.IdentNode)
+	if !ok {
+		return false
+	}
+
+	if ident.Ident == "recur" {
+		return true
+	}
+
+	if _, ok := t.group.index[ident.Ident]; !ok {
+		return false
+	}
+
+	// A parameter shadows the function's name
+	for _, param := range t.group.fns[t.current].Args[0].(*parser.VectorNode).Nodes {
+		if param.(*parser.IdentNode).Ident == ident.Ident {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (t *groupTarget) makeTailCall(node *parser.CallNode) []ast.Stmt {
+	idx := t.current
+	if name := node.Callee.(*parser.IdentNode).Ident; name != "recur" {
+		idx = t.group.index[name]
+	}
+
+	params := t.group.fns[idx].Args[0].(*parser.VectorNode).Nodes
+	if len(node.Args) != len(params) {
+		panic("wrong number of arguments in tail call to: " + t.group.names[idx])
+	}
+
+	args := makeVector(anyType, EvalExprs(node.Args))
+	jump := makeAssignStmt(h.E(t.group.fnIdx, t.group.args), h.E(makeIntLit(idx), args), token.ASSIGN)
+
+	return h.S(jump, makeBranchStmt(token.CONTINUE, nil))
+}
+
+// findTailGroups looks for mutually tail recursive top-level fns,
+// i.e. the strongly connected components of their tail calls.
+func findTailGroups(tree []parser.Node) []*tailGroup {
+	fns := map[string]*parser.CallNode{}
+	names := []string{}
+
+	for _, node := range tree {
+		name, fn := getDefdFunc(node)
+		if fn == nil || name == "main" {
+			continue
+		}
+
+		fns[name] = fn
+		names = append(names, name)
+	}
+
+	calls := map[string][]string{}
+	for _, name := range names {
+		target := &groupTarget{group: &tailGroup{fns: []*parser.CallNode{fns[name]}, index: map[string]int{}}}
+		for other := range fns {
+			target.group.index[other] = 0
+		}
+
+		eachTailCall(fns[name].Args[1:], func(node *parser.CallNode) {
+			if target.isTailCall(node) && !isCallTo(node, "recur") {
+				calls[name] = append(calls[name], node.Callee.(*parser.IdentNode).Ident)
+			}
+		})
+	}
+
+	groups := []*tailGroup{}
+	for _, component := range stronglyConnected(names, calls) {
+		if len(component) < 2 {
+			continue
+		}
+
+		group := &tailGroup{
+			dispatch: generateIdent(),
+			fnIdx:    generateIdent(),
+			args:     generateIdent(),
+			names:    component,
+			index:    map[string]int{},
+		}
+
+		for i, name := range component {
+			group.fns = append(group.fns, fns[name])
+			group.index[name] = i
+		}
+
+		groups = append(groups, group)
+	}
+
+	return groups
+}
+
+// getDefdFunc returns the fn of a (def name (fn ...)), as long
+// as it neither takes rest arguments nor destructures them.
+func getDefdFunc(node parser.Node) (string, *parser.CallNode) {
+	def, ok := node.(*parser.CallNode)
+	if !ok || !checkDefArgs(def) || len(def.Args) != 2 {
+		return "", nil
+	}
+
+	name, ok := def.Args[0].(*parser.IdentNode)
+	if !ok {
+		return "", nil
+	}
+
+	fn, ok := def.Args[1].(*parser.CallNode)
+	if !ok || !checkFuncArgs(fn) {
+		return "", nil
+	}
+
+	for _, param := range fn.Args[0].(*parser.VectorNode).Nodes {
+		if ident, ok := param.(*parser.IdentNode); !ok || ident.Ident == "&" {
+			return "", nil
+		}
+	}
+
+	return name.Ident, fn
+}
+
+// stronglyConnected is Tarjan's algorithm, the components
+// are returned in the order the nodes were given.
+func stronglyConnected(nodes []string, edges map[string][]string) [][]string {
+	index := map[string]int{}
+	lowlink := map[string]int{}
+	onStack := map[string]bool{}
+	stack := []string{}
+	components := [][]string{}
+
+	var connect func(string)
+	connect = func(v string) {
+		index[v] = len(index)
+		lowlink[v] = index[v]
+		stack = append(stack, v)
+		onStack[v] = true
+
+		for _, w := range edges[v] {
+			if _, seen := index[w]; !seen {
+				connect(w)
+				lowlink[v] = min(lowlink[v], lowlink[w])
+			} else if onStack[w] {
+				lowlink[v] = min(lowlink[v], index[w])
+			}
+		}
+
+		if lowlink[v] == index[v] {
+			component := []string{}
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				component = append(component, w)
+				if w == v {
+					break
+				}
+			}
+			components = append(components, component)
+		}
+	}
+
+	for _, v := range nodes {
+		if _, seen := index[v]; !seen {
+			connect(v)
+		}
+	}
+
+	// keep the functions of a component in source order
+	order := map[string]int{}
+	for i, v := range nodes {
+		order[v] = i
+	}
+	for _, component := range components {
+		for i := 1; i < len(component); i++ {
+			for j := i; j > 0 && order[component[j]] < order[component[j-1]]; j-- {
+				component[j], component[j-1] = component[j-1], component[j]
+			}
+		}
+	}
+
+	return components
+}
+
+// makeDispatchDecl generates the loop running the group:
+//
+//	func GEN0(GEN1 int, GEN2 []core.Any) core.Any {
+//		for {
+//			switch GEN1 {
+//			case 0:
+//				n := GEN2[0]
+//				...
+//			}
+//		}
+//	}
+func makeDispatchDecl(group *tailGroup) *ast.FuncDecl {
+	outerLoops := loopLabels
+	loopLabels = nil
+	defer func() { loopLabels = outerLoops }()
+
+	clauses := h.EmptyS()
+	catchErrs := false
+
+	for i, fn := range group.fns {
+		body := h.EmptyS()
+		for j, param := range fn.Args[0].(*parser.VectorNode).Nodes {
+			name := param.(*parser.IdentNode).Ident
+			if !searchForIdent(fn.Args[1:], name) {
+				continue
+			}
+
+			arg := &ast.IndexExpr{X: group.args, Index: makeIntLit(j)}
+			body = append(body, makeAssignStmt(h.E(makeLocalIdent(name)), h.E(arg), token.DEFINE))
+		}
+
+		pushLocals(boundNames(fn.Args[0]))
+		body = append(body, evalTailBody(fn.Args[1:], &groupTarget{group: group, current: i})...)
+		popLocals()
+		clauses = append(clauses, makeCaseClause(h.E(makeIntLit(i)), body))
+
+		catchErrs = catchErrs || searchForTryErr(fn.Args[1:])
+	}
+
+	loop := makeForStmt(nil, nil, nil, makeBlockStmt(h.S(makeSwitchStmt(group.fnIdx, makeBlockStmt(clauses)))))
+
+	params := makeFieldList([]*ast.Field{
+		makeField(h.I(group.fnIdx), ast.NewIdent("int")),
+		makeField(h.I(group.args), &ast.ArrayType{Elt: anyType}),
+	})
+	results := makeFieldList([]*ast.Field{makeField(nil, anyType)})
+	fn := makeFuncLit(makeFuncType(results, params), makeBlockStmt(h.S(loop)))
+
+	if catchErrs {
+		makeErrCatching(fn)
+	}
+
+	return makeFunDeclFromFuncLit(group.dispatch, fn)
+}
+
+// The functions of the group just enter the dispatch loop
+func makeGroupMemberDecl(group *tailGroup, name string) *ast.FuncDecl {
+	idx := group.index[name]
+	argIdents, _, _ := getArgIdentsFromVector(group.fns[idx].Args[0].(*parser.VectorNode))
+
+	args := make([]ast.Expr, len(argIdents))
+	for i, ident := range argIdents {
+		args[i] = ident
+	}
+
+	params := []*ast.Field{}
+	if len(argIdents) != 0 {
+		params = append(params, makeField(argIdents, anyType))
+	}
+
+	call := makeFuncCall(group.dispatch, h.E(makeIntLit(idx), makeVector(anyType, args)))
+	results := makeFieldList([]*ast.Field{makeField(nil, anyType)})
+	fn := makeFuncLit(makeFuncType(results, makeFieldList(params)), makeBlockStmt(h.S(makeReturnStmt(h.E(call)))))
+
+	return makeFunDeclFromFuncLit(makeIdomaticIdent(name), fn)
+}
+
+func searchForIdent(nodes []parser.Node, name string) bool {
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *parser.IdentNode:
+			if n.Ident == name {
+				return true
+			}
+		case *parser.CallNode:
+			if searchForIdent(append([]parser.Node{n.Callee}, n.Args...), name) {
+				return true
+			}
+		case *parser.VectorNode:
+			if searchForIdent(n.Nodes, name) {
+				return true
+			}
+		case *parser.MapNode:
+			if searchForIdent(n.Nodes, name) {
+				return true
+			}
+		case *parser.SetNode:
+			if searchForIdent(n.Nodes, name) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func makeIntLit(n int) *ast.BasicLit {
+	return makeBasicLit(token.INT, strconv.Itoa(n))
+}
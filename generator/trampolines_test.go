@@ -0,0 +1,28 @@
+package generator
+
+import "testing"
+
+func TestTailGroups(t *testing.T) {
+	runCompileTests(t, []compileTest{
+		{"mutual recursion", `
+(def my-even (fn [n] (if (= n 0) true (my-odd (- n 1)))))
+(def my-odd (fn [n] (if (= n 0) false (my-even (- n 1)))))`, ""},
+		{"three fns", `
+(def a (fn [n] (if (> n 0) (b (- n 1)) "a")))
+(def b (fn [n] (c n)))
+(def c (fn [n] (cond (> n 10) (a (- n 2)) :else (a n))))`, ""},
+		{"self and group", `
+(def ping (fn [n acc] (if (> n 0) (pong (- n 1) (+ acc 1)) acc)))
+(def pong (fn [n acc] (if (= n 5) (pong (- n 1) acc) (ping n acc))))`, ""},
+		{"not in tail position", `
+(def a (fn [n] (+ 1 (b n))))
+(def b (fn [n] (a n)))`, ""},
+		{"wrong arity", `
+(def a (fn [n] (b n n)))
+(def b (fn [n] (a n)))`, "wrong number of arguments in tail call"},
+		{"trampoline", `(def f (fn [n] (if (> n 0) (fn [] (f (- n 1))) n)))
+(def g (fn [] (trampoline f 10)))`, ""},
+		{"apply", `(def f (fn [] (apply fmt/println 1 [2 3])))`, ""},
+		{"apply rest", `(def sum-all (fn [& xs] (apply core/ADD xs)))`, ""},
+	})
+}
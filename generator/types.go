@@ -0,0 +1,279 @@
+package generator
+
+import (
+	"github.com/jcla1/gisp/parser"
+	h "github.com/jcla1/gisp/generator/helpers"
+	"go/ast"
+	goparser "go/parser"
+	"go/token"
+)
+
+// evalType turns a type written in gisp into a Go type:
+//
+//	int, fmt/Stringer  named types
+//	^map[string]int    Go's own syntax
+//	(* int)            *int
+//	(slice int)        []int
+//	(array 3 int)      [3]int
+//	(map string int)   map[string]int
+//	(chan int)         chan int
+//	(<-chan int)       <-chan int
+//	(func [int & any] [bool error])  func(int, ...any) (bool, error)
+//	(interface)        interface{}
+func evalType(node parser.Node) ast.Expr {
+	switch n := node.(type) {
+	case *parser.IdentNode:
+		return makeIdomaticSelector(n.Ident)
+
+	case *parser.TypeHintNode:
+		typ, err := goparser.ParseExpr(n.Expr)
+		if err != nil {
+			panic("invalid type: " + n.String())
+		}
+		return typ
+
+	case *parser.NilNode:
+		// only in a type-case
+		return ast.NewIdent("nil")
+
+	case *parser.CallNode:
+		switch {
+		case isCallTo(n, "*") && len(n.Args) == 1:
+			return makeStarExpr(evalType(n.Args[0]))
+
+		case isCallTo(n, "slice") && len(n.Args) == 1:
+			return &ast.ArrayType{Elt: evalType(n.Args[0])}
+
+		case isCallTo(n, "array") && len(n.Args) == 2:
+			return &ast.ArrayType{Len: EvalExpr(n.Args[0]), Elt: evalType(n.Args[1])}
+
+		case isCallTo(n, "map") && len(n.Args) == 2:
+			return makeMapType(evalType(n.Args[0]), evalType(n.Args[1]))
+
+		case isCallTo(n, "chan") && len(n.Args) == 1:
+			return &ast.ChanType{Dir: ast.SEND | ast.RECV, Value: evalType(n.Args[0])}
+
+		case isCallTo(n, "<-chan") && len(n.Args) == 1:
+			return &ast.ChanType{Dir: ast.RECV, Value: evalType(n.Args[0])}
+
+		case isCallTo(n, "chan<-") && len(n.Args) == 1:
+			return &ast.ChanType{Dir: ast.SEND, Value: evalType(n.Args[0])}
+
+		case isCallTo(n, "func") && len(n.Args) >= 1 && len(n.Args) <= 2:
+			return makeFuncTypeFromNodes(n.Args)
+
+		case isCallTo(n, "interface") && len(n.Args) == 0:
+			return &ast.InterfaceType{Methods: makeFieldList(nil)}
+		}
+	}
+
+	panic("not a type: " + node.String())
+}
+
+// The parameters are a vector, possibly ending in & T, while the
+// results are a single type or a vector of them.
+func makeFuncTypeFromNodes(nodes []parser.Node) *ast.FuncType {
+	paramTypes, ok := nodes[0].(*parser.VectorNode)
+	if !ok {
+		panic("func types need a vector of parameter types!")
+	}
+
+	params := []*ast.Field{}
+	for i := 0; i < len(paramTypes.Nodes); i++ {
+		if isIdent(paramTypes.Nodes[i], "&") {
+			if i != len(paramTypes.Nodes)-2 {
+				panic("& has to be followed by the last parameter type!")
+			}
+			params = append(params, makeField(nil, makeEllipsis(evalType(paramTypes.Nodes[i+1]))))
+			break
+		}
+		params = append(params, makeField(nil, evalType(paramTypes.Nodes[i])))
+	}
+
+	var results *ast.FieldList
+	if len(nodes) == 2 {
+		resultTypes := []parser.Node{nodes[1]}
+		if vect, ok := nodes[1].(*parser.VectorNode); ok {
+			resultTypes = vect.Nodes
+		}
+
+		fields := []*ast.Field{}
+		for _, typ := range resultTypes {
+			fields = append(fields, makeField(nil, evalType(typ)))
+		}
+		results = makeFieldList(fields)
+	}
+
+	return makeFuncType(results, makeFieldList(params))
+}
+
+func isAnyType(node parser.Node) bool {
+	return isIdent(node, "any") || isIdent(node, "core/Any")
+}
+
+// makeTypedExpr evaluates node as a value of type typ, literals are
+// left untyped, anything else is asserted: core.Any(x).(T)
+func makeTypedExpr(node parser.Node, typ parser.Node) ast.Expr {
+	expr := EvalExpr(node)
+	if _, asserted := expr.(*ast.TypeAssertExpr); asserted || isAnyType(typ) {
+		return expr
+	}
+
+	switch node.Type() {
+	case parser.NodeNumber, parser.NodeString, parser.NodeChar, parser.NodeBool:
+		return expr
+	}
+
+	return makeTypeAssertion(makeFuncCall(anyType, h.E(expr)), evalType(typ))
+}
+
+var intType = parser.NewIdentNode("int")
+
+// The numeric types, whose conversions go through core.As, as the
+// number converted is usually held in a core.Any.
+var numericTypes = []string{
+	"int", "int8", "int16", "int32", "int64", "rune",
+	"uint", "uint8", "uint16", "uint32", "uint64", "uintptr", "byte",
+	"float32", "float64", "complex64", "complex128",
+}
+
+func isNumericConversion(node *parser.CallNode) bool {
+	ident, ok := node.Callee.(*parser.IdentNode)
+	if !ok || !isInSlice(ident.Ident, numericTypes) || len(node.Args) != 1 {
+		return false
+	}
+
+	t := node.Args[0].Type()
+	return t != parser.NodeNumber && t != parser.NodeChar
+}
+
+// (int x) becomes core.As[int](x), converting any kind of number
+func makeNumericConversion(node *parser.CallNode) ast.Expr {
+	return makeAs(evalType(node.Callee), EvalExpr(node.Args[0]))
+}
+
+func makeIntExpr(node parser.Node) ast.Expr {
+	return makeTypedExpr(node, intType)
+}
+
+func makeTypedExprs(nodes []parser.Node, typ parser.Node) []ast.Expr {
+	out := make([]ast.Expr, len(nodes))
+	for i, node := range nodes {
+		out[i] = makeTypedExpr(node, typ)
+	}
+	return out
+}
+
+// (assert-ok T x) returns x as a T and whether it is one, so it
+// can be bound like: (if-ok [s (assert-ok string x)] ...)
+func makeAssertOk(node *parser.CallNode) *ast.CallExpr {
+	typ := evalType(node.Args[0])
+	value, ok := generateIdent(), generateIdent()
+
+	// x may not be an interface, a let bound int for one
+	x := makeFuncCall(anyType, h.E(EvalExpr(node.Args[1])))
+	assign := makeAssignStmt(h.E(value, ok), h.E(makeTypeAssertion(x, typ)), token.DEFINE)
+	results := makeFieldList([]*ast.Field{makeField(nil, typ), makeField(nil, ast.NewIdent("bool"))})
+	fn := makeFuncLit(makeFuncType(results, nil), makeBlockStmt(h.S(assign, makeReturnStmt(h.E(value, ok)))))
+
+	return makeFuncCall(fn, h.EmptyE())
+}
+
+func checkTypeCaseArgs(node *parser.CallNode) bool {
+	if !isCallTo(node, "type-case") {
+		return false
+	}
+
+	if len(node.Args) < 1 || len(node.Args)%2 != 1 {
+		panic("type-case needs an expression followed by [type name]/expression pairs!")
+	}
+
+	for i := 1; i < len(node.Args); i += 2 {
+		if isElseKeyword(node.Args[i]) {
+			if i != len(node.Args)-2 {
+				panic("type-case's :else has to be the last clause!")
+			}
+			continue
+		}
+
+		clause, ok := node.Args[i].(*parser.VectorNode)
+		if !ok || len(clause.Nodes) < 1 || len(clause.Nodes) > 2 {
+			panic("type-case clauses should look like: [type name]")
+		}
+
+		if len(clause.Nodes) == 2 && clause.Nodes[1].Type() != parser.NodeIdent {
+			panic("type-case can only bind an identifier: " + clause.String())
+		}
+	}
+
+	return true
+}
+
+func makeTypeCaseFunc(node *parser.CallNode) *ast.CallExpr {
+	switchStmt := makeTypeSwitch(node, func(expr parser.Node) []ast.Stmt {
+		return h.S(makeReturnStmt(h.E(EvalExpr(expr))))
+	})
+
+	return makeClosureCall(h.S(switchStmt))
+}
+
+// makeTypeSwitch generates a type-case, the clauses only binding
+// their name if they use it:
+//
+//	switch GEN0 := x.(type) {
+//	case int:
+//		n := GEN0
+//		...
+//	default:
+//		return nil
+//	}
+func makeTypeSwitch(node *parser.CallNode, evalClause func(parser.Node) []ast.Stmt) *ast.TypeSwitchStmt {
+	value := generateIdent()
+	bound, hasDefault := false, false
+	clauses := h.EmptyS()
+
+	for i := 1; i < len(node.Args); i += 2 {
+		expr := node.Args[i+1]
+		if isElseKeyword(node.Args[i]) {
+			hasDefault = true
+			clauses = append(clauses, makeCaseClause(nil, evalClause(expr)))
+			continue
+		}
+
+		clause := node.Args[i].(*parser.VectorNode).Nodes
+		body := h.EmptyS()
+		names := []string{}
+
+		if len(clause) == 2 {
+			name := clause[1].(*parser.IdentNode).Ident
+			names = append(names, name)
+
+			if searchForIdent([]parser.Node{expr}, name) {
+				body = append(body, makeAssignStmt(h.E(makeLocalIdent(name)), h.E(value), token.DEFINE))
+				bound = true
+			}
+		}
+
+		pushLocals(names)
+		body = append(body, evalClause(expr)...)
+		popLocals()
+
+		clauses = append(clauses, makeCaseClause(h.E(evalType(clause[0])), body))
+	}
+
+	if !hasDefault {
+		clauses = append(clauses, makeCaseClause(nil, h.S(makeReturnStmt(h.E(ast.NewIdent("nil"))))))
+	}
+
+	// as with assert-ok, x needn't be an interface
+	x := makeFuncCall(anyType, h.E(EvalExpr(node.Args[0])))
+	var assign ast.Stmt = makeExprStmt(makeTypeAssertion(x, nil))
+	if bound {
+		assign = makeAssignStmt(h.E(value), h.E(makeTypeAssertion(x, nil)), token.DEFINE)
+	}
+
+	return &ast.TypeSwitchStmt{
+		Assign: assign,
+		Body:   makeBlockStmt(clauses),
+	}
+}
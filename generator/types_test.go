@@ -0,0 +1,44 @@
+package generator
+
+import "testing"
+
+func TestTypes(t *testing.T) {
+	runCompileTests(t, []compileTest{
+		{"pointer", `(def f (fn [x] (assert (* os/File) x)))`, ""},
+		{"slice", `(def f (fn [x] (assert (slice string) x)))`, ""},
+		{"map", `(def f (fn [x] (assert (map string int) x)))`, ""},
+		{"chans", `(def f (fn [x y z] (fmt/sprint (assert (chan int) x) (assert (<-chan int) y) (assert (chan<- int) z))))`, ""},
+		{"func", `(def f (fn [x] (assert (func [int & any] [bool error]) x)))`, ""},
+		{"func single result", `(def f (fn [x] (assert (func [] int) x)))`, ""},
+		{"interface", `(def f (fn [x] (assert (interface) x)))`, ""},
+		{"func params", `(def f (fn [x] (assert (func int) x)))`, "vector of parameter types"},
+		{"func rest", `(def f (fn [x] (assert (func [& int string]) x)))`, "followed by the last parameter type"},
+		{"not a type", `(def f (fn [x] (assert "int" x)))`, "first argument needs to be a type"},
+		{"assert args", `(def f (fn [x] (assert int)))`, "assert needs 2 arguments"},
+	})
+}
+
+func TestAssertOk(t *testing.T) {
+	runCompileTests(t, []compileTest{
+		{"if-ok", `(def f (fn [x] (if-ok [s (assert-ok string x)] s "")))`, ""},
+		{"value", `(def f (fn [x] (fmt/sprint (assert-ok int x))))`, ""},
+		{"literal", `(def f (fn [] (fmt/sprint (assert-ok string "hey"))))`, ""},
+		{"let bound", `(def f (fn [] (let [[x 5]] (fmt/sprint (assert-ok int x)))))`, ""},
+		{"args", `(def f (fn [x] (assert-ok x)))`, "assert-ok needs 2 arguments"},
+	})
+}
+
+func TestTypeCase(t *testing.T) {
+	runCompileTests(t, []compileTest{
+		{"type-case", `(def f (fn [x] (type-case x [int n] (+ n 1) [string s] (len s) [nil] 0 :else -1)))`, ""},
+		{"unused name", `(def f (fn [x] (type-case x [int n] "int" [error] "error")))`, ""},
+		{"tail call", `(def f (fn [x] (type-case x [(slice any) xs] (f (len xs)) :else x)))`, ""},
+		{"statement", `(def f (fn [x] (dotimes [i 3] (type-case x [int n] (fmt/sprint n))) x))`, ""},
+		{"let bound", `(def f (fn [] (let [[x 5]] (type-case x [int n] (+ n 1) :else 0))))`, ""},
+		{"literal", `(def f (fn [] (type-case "s" [string s] s [int] "int")))`, ""},
+		{"odd", `(def f (fn [x] (type-case x [int n])))`, "[type name]/expression pairs"},
+		{"else not last", `(def f (fn [x] (type-case x :else 1 [int n] n)))`, "last clause"},
+		{"bad clause", `(def f (fn [x] (type-case x int 1)))`, "should look like: [type name]"},
+		{"bad name", `(def f (fn [x] (type-case x [int "n"] 1)))`, "can only bind an identifier"},
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/jcla1/gisp/parser"
 	"bufio"
 	"bytes"
+	"flag"
 	"fmt"
 	"go/ast"
 	"go/printer"
@@ -31,8 +32,11 @@ func args(filename string) {
 }
 
 func main() {
-	if len(os.Args) > 1 {
-		args(os.Args[1])
+	flag.BoolVar(&generator.ExportAll, "export-all", false, "export all defs not tagged ^:private")
+	flag.Parse()
+
+	if flag.NArg() > 0 {
+		args(flag.Arg(0))
 		return
 	}
 
@@ -45,7 +49,7 @@ func main() {
 		fmt.Println(p)
 
 		// a := generator.GenerateAST(p)
-		a := generator.EvalExprs(p)
+		a := generator.EvalExprs(generator.ExpandThreading(p))
 		fset := token.NewFileSet()
 		ast.Print(fset, a)
 
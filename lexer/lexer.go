@@ -1,7 +1,10 @@
 package lexer
 
 import (
+	"errors"
 	"fmt"
+	"math/big"
+	"strconv"
 	"strings"
 	"unicode"
 	"unicode/utf8"
@@ -25,13 +28,24 @@ const (
 	ItemRightParen
 	ItemLeftVect
 	ItemRightVect
+	ItemLeftMap
+	ItemRightMap
+	ItemLeftSet
+	ItemLeftFn
+	ItemDiscard
+	ItemMeta
+	ItemTypeHint
 
 	ItemIdent
+	ItemKeyword
 	ItemString
+	ItemRegex
 	ItemChar
 	ItemFloat
 	ItemInt
 	ItemComplex
+	ItemBigInt
+	ItemRatio
 
 	ItemQuote
 	ItemQuasiQuote
@@ -108,10 +122,22 @@ func (l *Lexer) acceptRun(valid string) {
 }
 
 func (l *Lexer) errorf(format string, args ...interface{}) stateFn {
-	l.items <- Item{ItemError, l.start, fmt.Sprintf(format, args...)}
+	return l.errorAt(l.start, format, args...)
+}
+
+// errorAt reports an error at pos, prefixed with its file:line:column
+func (l *Lexer) errorAt(pos Pos, format string, args ...interface{}) stateFn {
+	msg := fmt.Sprintf("%s: %s", l.position(pos), fmt.Sprintf(format, args...))
+	l.items <- Item{ItemError, pos, msg}
 	return nil
 }
 
+func (l *Lexer) position(pos Pos) string {
+	line := 1 + strings.Count(l.input[:pos], "\n")
+	col := 1 + utf8.RuneCountInString(l.input[strings.LastIndex(l.input[:pos], "\n")+1:pos])
+	return fmt.Sprintf("%s:%d:%d", l.name, line, col)
+}
+
 func (l *Lexer) NextItem() Item {
 	item := <-l.items
 	l.lastPos = item.Pos
@@ -147,6 +173,18 @@ func lexRightVect(l *Lexer) stateFn {
 	return lexWhitespace
 }
 
+func lexLeftMap(l *Lexer) stateFn {
+	l.emit(ItemLeftMap)
+
+	return lexWhitespace
+}
+
+func lexRightMap(l *Lexer) stateFn {
+	l.emit(ItemRightMap)
+
+	return lexWhitespace
+}
+
 // lexes an open parenthesis
 func lexLeftParen(l *Lexer) stateFn {
 	l.emit(ItemLeftParen)
@@ -173,12 +211,39 @@ func lexWhitespace(l *Lexer) stateFn {
 		return lexLeftVect
 	case r == ']':
 		return lexRightVect
+	case r == '{':
+		return lexLeftMap
+	case r == '}':
+		return lexRightMap
 	case r == '"':
 		return lexString
-	case r == '+' || r == '-' || ('0' <= r && r <= '9'):
+	case r == '`':
+		return lexRawString
+	case r == '\\':
+		return lexChar
+	case r == '+' || r == '-':
+		// a sign only starts a number if a digit follows
+		if d := l.peek(); '0' <= d && d <= '9' {
+			l.backup()
+			return lexNumber
+		}
+		return lexIdentifier
+	case '0' <= r && r <= '9':
+		l.backup()
 		return lexNumber
 	case r == ';':
 		return lexComment
+	case r == ':':
+		return lexKeyword
+	case r == '#':
+		return lexDispatch
+	case r == '^':
+		// ^:export and ^{...} are metadata, anything else a Go type
+		if next := l.peek(); next != ':' && next != '{' {
+			return lexTypeHint
+		}
+		l.emit(ItemMeta)
+		return lexWhitespace
 	case isAlphaNumeric(r):
 		return lexIdentifier
 	default:
@@ -186,11 +251,73 @@ func lexWhitespace(l *Lexer) stateFn {
 	}
 }
 
-func lexString(l *Lexer) stateFn {
+// lex a reader macro, the dispatch character "#" is known to be already read
+func lexDispatch(l *Lexer) stateFn {
+	switch r := l.next(); r {
+	case '{':
+		l.emit(ItemLeftSet)
+	case '(':
+		l.emit(ItemLeftFn)
+	case '_':
+		l.emit(ItemDiscard)
+	case '"':
+		return lexRegex
+	default:
+		return l.errorf("unknown dispatch macro: #%c", r)
+	}
+
+	return lexWhitespace
+}
+
+// lex a Go type following ^, i.e. ^[]int or ^map[string]T, the
+// "^" is known to be already read. Only within brackets the type
+// may contain spaces.
+func lexTypeHint(l *Lexer) stateFn {
+	depth := 0
+
+	for {
+		switch r := l.next(); {
+		case r == '[' || r == '(' || r == '{':
+			depth++
+		case (r == ']' || r == ')' || r == '}') && depth > 0:
+			depth--
+		case r == EOF && depth > 0:
+			return l.errorf("unterminated type after ^")
+		case r == EOF || r == ']' || r == ')' || r == '}' || ((isSpace(r) || r == '\n') && depth == 0):
+			l.backup()
+
+			if l.pos == l.start+1 {
+				return l.errorf("missing type after ^")
+			}
+
+			l.emit(ItemTypeHint)
+			return lexWhitespace
+		}
+	}
+}
+
+// lex a regex literal, its backslashes are left for the regexp package
+func lexRegex(l *Lexer) stateFn {
 	for r := l.next(); r != '"'; r = l.next() {
 		if r == '\\' {
 			r = l.next()
 		}
+		if r == EOF {
+			return l.errorf("unterminated regex literal")
+		}
+	}
+	l.emit(ItemRegex)
+	return lexWhitespace
+}
+
+func lexString(l *Lexer) stateFn {
+	for r := l.next(); r != '"'; r = l.next() {
+		if r == '\\' {
+			if state := l.scanEscape(); state != nil {
+				return state
+			}
+			continue
+		}
 		if r == EOF {
 			return l.errorf("unterminated quoted string")
 		}
@@ -199,6 +326,130 @@ func lexString(l *Lexer) stateFn {
 	return lexWhitespace
 }
 
+// scanEscape checks the escape sequence after a backslash, the same
+// way Go does. It returns an error state for invalid sequences.
+func (l *Lexer) scanEscape() stateFn {
+	pos := l.pos - 1
+
+	var digits int
+	var base, max uint32
+
+	switch r := l.next(); r {
+	case 'a', 'b', 'f', 'n', 'r', 't', 'v', '\\', '"':
+		return nil
+	case '0', '1', '2', '3', '4', '5', '6', '7':
+		l.backup()
+		digits, base, max = 3, 8, 255
+	case 'x':
+		digits, base, max = 2, 16, 255
+	case 'u':
+		digits, base, max = 4, 16, unicode.MaxRune
+	case 'U':
+		digits, base, max = 8, 16, unicode.MaxRune
+	case EOF:
+		return l.errorAt(pos, "unterminated quoted string")
+	default:
+		return l.errorAt(pos, "invalid escape sequence \"\\%c\" in string", r)
+	}
+
+	var x uint32
+	for i := 0; i < digits; i++ {
+		d := digitVal(l.next())
+		if d >= base {
+			return l.errorAt(pos, "invalid escape sequence \"%s\" in string", l.input[pos:l.pos])
+		}
+		x = x*base + d
+	}
+
+	if x > max || (base == 16 && 0xD800 <= x && x < 0xE000) {
+		return l.errorAt(pos, "escape sequence \"%s\" is an invalid code point", l.input[pos:l.pos])
+	}
+
+	return nil
+}
+
+func digitVal(r rune) uint32 {
+	switch {
+	case '0' <= r && r <= '9':
+		return uint32(r - '0')
+	case 'a' <= r && r <= 'f':
+		return uint32(r - 'a' + 10)
+	case 'A' <= r && r <= 'F':
+		return uint32(r - 'A' + 10)
+	}
+	return 16
+}
+
+// lex a raw string, the opening backquote is known to be already read.
+// Just like in Go, it may span multiple lines and has no escapes.
+func lexRawString(l *Lexer) stateFn {
+	for r := l.next(); r != '`'; r = l.next() {
+		if r == EOF {
+			return l.errorf("unterminated raw string")
+		}
+	}
+	l.emit(ItemString)
+	return lexWhitespace
+}
+
+// lex a character literal: \a, \newline or \u03bb,
+// the backslash is known to be already read
+func lexChar(l *Lexer) stateFn {
+	r := l.next()
+	if r == EOF || isSpace(r) || isEndOfLine(r) {
+		return l.errorf("missing character after \"\\\"")
+	}
+
+	// named characters and unicode escapes
+	if unicode.IsLetter(r) {
+		for r = l.next(); unicode.IsLetter(r) || unicode.IsDigit(r); r = l.next() {
+		}
+		l.backup()
+	}
+
+	if _, ok := CharValue(l.input[l.start+1 : l.pos]); !ok {
+		return l.errorf("unknown character literal: %s", l.input[l.start:l.pos])
+	}
+
+	l.emit(ItemChar)
+	return lexWhitespace
+}
+
+var charNames = map[string]rune{
+	"newline":   '\n',
+	"space":     ' ',
+	"tab":       '\t',
+	"return":    '\r',
+	"backspace": '\b',
+	"formfeed":  '\f',
+}
+
+// CharValue returns the rune of a character literal,
+// given without the leading backslash.
+func CharValue(name string) (rune, bool) {
+	if utf8.RuneCountInString(name) == 1 {
+		r, _ := utf8.DecodeRuneInString(name)
+		return r, true
+	}
+
+	if r, ok := charNames[name]; ok {
+		return r, true
+	}
+
+	if len(name) == 5 && name[0] == 'u' {
+		var r rune
+		for _, d := range name[1:] {
+			if digitVal(d) >= 16 {
+				return 0, false
+			}
+			r = r*16 + rune(digitVal(d))
+		}
+		return r, utf8.ValidRune(r)
+	}
+
+	return 0, false
+}
+
 func lexIdentifier(l *Lexer) stateFn {
 	for r := l.next(); isAlphaNumeric(r); r = l.next() {
 	}
@@ -208,6 +459,20 @@ func lexIdentifier(l *Lexer) stateFn {
 	return lexWhitespace
 }
 
+// lex a keyword, the colon is known to be already read
+func lexKeyword(l *Lexer) stateFn {
+	for r := l.next(); isAlphaNumeric(r); r = l.next() {
+	}
+	l.backup()
+
+	if l.start+1 == l.pos {
+		return l.errorf("missing keyword name after \":\"")
+	}
+
+	l.emit(ItemKeyword)
+	return lexWhitespace
+}
+
 // lex a close parenthesis
 func lexRightParen(l *Lexer) stateFn {
 	l.emit(ItemRightParen)
@@ -228,45 +493,89 @@ func lexNumber(l *Lexer) stateFn {
 		return l.errorf("bad number syntax: %q", l.input[l.start:l.pos])
 	}
 
-	if l.start+1 == l.pos {
-		return lexIdentifier
-	}
+	num := l.input[l.start:l.pos]
 
-	if sign := l.peek(); sign == '+' || sign == '-' {
+	switch {
+	case l.peek() == '+' || l.peek() == '-':
 		// Complex: 1+2i. No spaces, must end in 'i'.
 		if !l.scanNumber() || l.input[l.pos-1] != 'i' {
 			return l.errorf("bad number syntax: %q", l.input[l.start:l.pos])
 		}
 		l.emit(ItemComplex)
-	} else if strings.ContainsRune(l.input[l.start:l.pos], '.') {
+	case strings.HasSuffix(num, "N"):
+		if _, ok := new(big.Int).SetString(num[:len(num)-1], 0); !ok {
+			return l.errorf("bad big integer syntax: %q", num)
+		}
+		l.emit(ItemBigInt)
+	case strings.ContainsRune(num, '/'):
+		if _, ok := new(big.Rat).SetString(num); !ok {
+			return l.errorf("bad ratio syntax: %q", num)
+		}
+		l.emit(ItemRatio)
+	case strings.HasSuffix(num, "i"):
+		l.emit(ItemComplex)
+	case isFloat(num):
+		if _, err := strconv.ParseFloat(num, 64); err != nil && !errors.Is(err, strconv.ErrRange) {
+			return l.errorf("bad number syntax: %q", num)
+		}
 		l.emit(ItemFloat)
-	} else {
+	default:
+		if _, ok := new(big.Int).SetString(num, 0); !ok {
+			return l.errorf("bad number syntax: %q", num)
+		}
 		l.emit(ItemInt)
 	}
 
 	return lexWhitespace
 }
 
+func isFloat(num string) bool {
+	if strings.HasPrefix(strings.TrimLeft(num, "+-"), "0x") || strings.HasPrefix(strings.TrimLeft(num, "+-"), "0X") {
+		return strings.ContainsAny(num, ".pP")
+	}
+
+	return strings.ContainsAny(num, ".eE")
+}
+
+// scanNumber accepts Go's number syntax (0x, 0o and 0b prefixes, _ as digit
+// separator) as well as a trailing N for big integers and n/d ratios.
 func (l *Lexer) scanNumber() bool {
 	// Optional leading sign.
 	l.accept("+-")
-	// Is it hex?
-	digits := "0123456789"
-	if l.accept("0") && l.accept("xX") {
-		digits = "0123456789abcdefABCDEF"
+	digits := "0123456789_"
+	decimal := true
+	if l.accept("0") {
+		switch {
+		case l.accept("xX"):
+			digits, decimal = "0123456789abcdefABCDEF_", false
+		case l.accept("oO"):
+			digits, decimal = "01234567_", false
+		case l.accept("bB"):
+			digits, decimal = "01_", false
+		}
 	}
 	l.acceptRun(digits)
 	if l.accept(".") {
 		l.acceptRun(digits)
 	}
-	if l.accept("eE") {
+	if decimal && l.accept("eE") || !decimal && l.accept("pP") {
 		l.accept("+-")
-		l.acceptRun("0123456789")
+		l.acceptRun("0123456789_")
 	}
+
+	switch {
+	// Is it a ratio?
+	case decimal && l.peek() == '/' && int(l.pos)+1 < len(l.input) && '0' <= l.input[l.pos+1] && l.input[l.pos+1] <= '9':
+		l.next()
+		l.acceptRun("0123456789")
 	// Is it imaginary?
-	l.accept("i")
-	// Next thing mustn't be alphanumeric.
-	if r := l.peek(); isAlphaNumeric(r) {
+	case l.accept("i"):
+	// Is it a big integer?
+	case l.accept("N"):
+	}
+
+	// Next thing mustn't be alphanumeric, other than the sign of a complex.
+	if r := l.peek(); isAlphaNumeric(r) && r != '+' && r != '-' {
 		l.next()
 		return false
 	}
@@ -285,7 +594,7 @@ func isEndOfLine(r rune) bool {
 
 // isAlphaNumeric reports whether r is a valid rune for an identifier.
 func isAlphaNumeric(r rune) bool {
-	return r == '>' || r == '<' || r == '=' || r == '-' || r == '+' || r == '*' || r == '&' || r == '_' || r == '/' || unicode.IsLetter(r) || unicode.IsDigit(r)
+	return r == '>' || r == '<' || r == '=' || r == '-' || r == '+' || r == '*' || r == '&' || r == '_' || r == '/' || r == '?' || r == '!' || r == '\'' || r == '%' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r)
 }
 
 func debug(msg string) {
@@ -0,0 +1,213 @@
+package lexer
+
+import (
+	"reflect"
+	"testing"
+)
+
+// lexAll returns the items of input up to and including the EOF or
+// the first error, with the positions left out.
+func lexAll(input string) []Item {
+	l := Lex("test", input)
+
+	var items []Item
+	for {
+		item := l.NextItem()
+		item.Pos = 0
+		items = append(items, item)
+
+		if item.Type == ItemEOF || item.Type == ItemError {
+			return items
+		}
+	}
+}
+
+var lexTests = []struct {
+	name  string
+	input string
+	items []Item
+}{
+	{"empty", "", []Item{
+		{ItemEOF, 0, ""},
+	}},
+	{"call", "(+ a 12)", []Item{
+		{ItemLeftParen, 0, "("},
+		{ItemIdent, 0, "+"},
+		{ItemIdent, 0, "a"},
+		{ItemInt, 0, "12"},
+		{ItemRightParen, 0, ")"},
+		{ItemEOF, 0, ""},
+	}},
+	{"vector", "[x \"y\"]", []Item{
+		{ItemLeftVect, 0, "["},
+		{ItemIdent, 0, "x"},
+		{ItemString, 0, `"y"`},
+		{ItemRightVect, 0, "]"},
+		{ItemEOF, 0, ""},
+	}},
+	{"keyword", "(case x :a 1)", []Item{
+		{ItemLeftParen, 0, "("},
+		{ItemIdent, 0, "case"},
+		{ItemIdent, 0, "x"},
+		{ItemKeyword, 0, ":a"},
+		{ItemInt, 0, "1"},
+		{ItemRightParen, 0, ")"},
+		{ItemEOF, 0, ""},
+	}},
+	{"map", "{:a x}", []Item{
+		{ItemLeftMap, 0, "{"},
+		{ItemKeyword, 0, ":a"},
+		{ItemIdent, 0, "x"},
+		{ItemRightMap, 0, "}"},
+		{ItemEOF, 0, ""},
+	}},
+	{"dispatch", `#{a} #(f %) #_ b #"a\"b"`, []Item{
+		{ItemLeftSet, 0, "#{"},
+		{ItemIdent, 0, "a"},
+		{ItemRightMap, 0, "}"},
+		{ItemLeftFn, 0, "#("},
+		{ItemIdent, 0, "f"},
+		{ItemIdent, 0, "%"},
+		{ItemRightParen, 0, ")"},
+		{ItemDiscard, 0, "#_"},
+		{ItemIdent, 0, "b"},
+		{ItemRegex, 0, `#"a\"b"`},
+		{ItemEOF, 0, ""},
+	}},
+	{"unknown dispatch", "#x", []Item{
+		{ItemError, 0, "test:1:1: unknown dispatch macro: #x"},
+	}},
+	{"unterminated regex", `#"a`, []Item{
+		{ItemError, 0, "test:1:1: unterminated regex literal"},
+	}},
+	{"numbers", "1 -5 0x1F 0o17 0b101 1_000 1.5e3 0x1p-2 2i 1+2i 123N 1/3 -2/4", []Item{
+		{ItemInt, 0, "1"},
+		{ItemInt, 0, "-5"},
+		{ItemInt, 0, "0x1F"},
+		{ItemInt, 0, "0o17"},
+		{ItemInt, 0, "0b101"},
+		{ItemInt, 0, "1_000"},
+		{ItemFloat, 0, "1.5e3"},
+		{ItemFloat, 0, "0x1p-2"},
+		{ItemComplex, 0, "2i"},
+		{ItemComplex, 0, "1+2i"},
+		{ItemBigInt, 0, "123N"},
+		{ItemRatio, 0, "1/3"},
+		{ItemRatio, 0, "-2/4"},
+		{ItemEOF, 0, ""},
+	}},
+	{"signs", "(- -x +)", []Item{
+		{ItemLeftParen, 0, "("},
+		{ItemIdent, 0, "-"},
+		{ItemIdent, 0, "-x"},
+		{ItemIdent, 0, "+"},
+		{ItemRightParen, 0, ")"},
+		{ItemEOF, 0, ""},
+	}},
+	{"bad number", "12a", []Item{
+		{ItemError, 0, `test:1:1: bad number syntax: "12a"`},
+	}},
+	{"bad binary", "0b12", []Item{
+		{ItemError, 0, `test:1:1: bad number syntax: "0b12"`},
+	}},
+	{"bad ratio", "1/0", []Item{
+		{ItemError, 0, `test:1:1: bad ratio syntax: "1/0"`},
+	}},
+	{"punctuation", "(reset! x' even? %2)", []Item{
+		{ItemLeftParen, 0, "("},
+		{ItemIdent, 0, "reset!"},
+		{ItemIdent, 0, "x'"},
+		{ItemIdent, 0, "even?"},
+		{ItemIdent, 0, "%2"},
+		{ItemRightParen, 0, ")"},
+		{ItemEOF, 0, ""},
+	}},
+	{"meta", "(def ^:export x/.y)", []Item{
+		{ItemLeftParen, 0, "("},
+		{ItemIdent, 0, "def"},
+		{ItemMeta, 0, "^"},
+		{ItemKeyword, 0, ":export"},
+		{ItemIdent, 0, "x/.y"},
+		{ItemRightParen, 0, ")"},
+		{ItemEOF, 0, ""},
+	}},
+	{"type hints", "[xs ^[]int m ^map[string]T f ^func(a, b int) error]", []Item{
+		{ItemLeftVect, 0, "["},
+		{ItemIdent, 0, "xs"},
+		{ItemTypeHint, 0, "^[]int"},
+		{ItemIdent, 0, "m"},
+		{ItemTypeHint, 0, "^map[string]T"},
+		{ItemIdent, 0, "f"},
+		{ItemTypeHint, 0, "^func(a, b int)"},
+		{ItemIdent, 0, "error"},
+		{ItemRightVect, 0, "]"},
+		{ItemEOF, 0, ""},
+	}},
+	{"missing type", "(f ^)", []Item{
+		{ItemLeftParen, 0, "("},
+		{ItemIdent, 0, "f"},
+		{ItemError, 0, "test:1:4: missing type after ^"},
+	}},
+	{"unterminated type", "^[]int{", []Item{
+		{ItemError, 0, "test:1:1: unterminated type after ^"},
+	}},
+	{"comment", "a ; b\nc", []Item{
+		{ItemIdent, 0, "a"},
+		{ItemIdent, 0, "c"},
+		{ItemEOF, 0, ""},
+	}},
+	{"missing keyword name", "(: a)", []Item{
+		{ItemLeftParen, 0, "("},
+		{ItemError, 0, `test:1:2: missing keyword name after ":"`},
+	}},
+	{"chars", `\a \( \newline \u03bb \λ`, []Item{
+		{ItemChar, 0, `\a`},
+		{ItemChar, 0, `\(`},
+		{ItemChar, 0, `\newline`},
+		{ItemChar, 0, `\u03bb`},
+		{ItemChar, 0, `\λ`},
+		{ItemEOF, 0, ""},
+	}},
+	{"unknown char", `(a\n \nope)`, []Item{
+		{ItemLeftParen, 0, "("},
+		{ItemIdent, 0, "a"},
+		{ItemChar, 0, `\n`},
+		{ItemError, 0, `test:1:6: unknown character literal: \nope`},
+	}},
+	{"missing char", `\ `, []Item{
+		{ItemError, 0, `test:1:1: missing character after "\"`},
+	}},
+	{"raw string", "`a\\n\nb`", []Item{
+		{ItemString, 0, "`a\\n\nb`"},
+		{ItemEOF, 0, ""},
+	}},
+	{"unterminated raw string", "`a", []Item{
+		{ItemError, 0, "test:1:1: unterminated raw string"},
+	}},
+	{"escapes", `"\t\"\\\101\x41\u00e9\U0001F600"`, []Item{
+		{ItemString, 0, `"\t\"\\\101\x41\u00e9\U0001F600"`},
+		{ItemEOF, 0, ""},
+	}},
+	{"invalid escape", `a\n"a\q"`, []Item{
+		{ItemIdent, 0, "a"},
+		{ItemChar, 0, `\n`},
+		{ItemError, 0, `test:1:6: invalid escape sequence "\q" in string`},
+	}},
+	{"short escape", `"\x4"`, []Item{
+		{ItemError, 0, `test:1:2: invalid escape sequence "\x4"" in string`},
+	}},
+	{"surrogate escape", `"\ud800"`, []Item{
+		{ItemError, 0, `test:1:2: escape sequence "\ud800" is an invalid code point`},
+	}},
+	{"unterminated string", `"abc`, []Item{
+		{ItemError, 0, "test:1:1: unterminated quoted string"},
+	}},
+}
+
+func TestLex(t *testing.T) {
+	for _, test := range lexTests {
+		if items := lexAll(test.input); !reflect.DeepEqual(items, test.items) {
+			t.Errorf("%s: got\n\t%v\nwant\n\t%v", test.name, items, test.items)
+		}
+	}
+}
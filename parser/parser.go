@@ -27,30 +27,118 @@ func (t NodeType) Type() NodeType {
 
 const (
 	NodeIdent NodeType = iota
+	NodeKeyword
 	NodeString
+	NodeChar
+	NodeBool
+	NodeNil
 	NodeNumber
+	NodeBigInt
+	NodeRatio
 	NodeCall
 	NodeVector
+	NodeMap
+	NodeSet
+	NodeRegex
+	NodeTypeHint
 )
 
 type IdentNode struct {
 	// Pos
 	NodeType
 	Ident string
+	// the forms given with ^, i.e. ^:export
+	Meta []Node
 }
 
 func (node *IdentNode) Copy() Node {
-	return NewIdentNode(node.Ident)
+	return &IdentNode{NodeType: node.Type(), Ident: node.Ident, Meta: node.Meta}
 }
 
-func (node *IdentNode) String() string {
-	if node.Ident == "nil" {
-		return "()"
+// HasMeta reports whether the identifier was tagged with ^:key
+// or ^{:key true}.
+func (node *IdentNode) HasMeta(key string) bool {
+	for _, meta := range node.Meta {
+		switch m := meta.(type) {
+		case *KeywordNode:
+			if m.Name == key {
+				return true
+			}
+		case *MapNode:
+			for i := 0; i+1 < len(m.Nodes); i += 2 {
+				k, ok := m.Nodes[i].(*KeywordNode)
+				v, isBool := m.Nodes[i+1].(*BoolNode)
+				if ok && k.Name == key && isBool && v.Value {
+					return true
+				}
+			}
+		}
 	}
 
+	return false
+}
+
+func (node *IdentNode) String() string {
 	return node.Ident
 }
 
+// TypeHintNode is a Go type written after a ^, it follows the
+// parameter or callee it applies to.
+type TypeHintNode struct {
+	// Pos
+	NodeType
+	Expr string
+}
+
+func (node *TypeHintNode) Copy() Node {
+	return newTypeHintNode(node.Expr)
+}
+
+func (node *TypeHintNode) String() string {
+	return "^" + node.Expr
+}
+
+type BoolNode struct {
+	// Pos
+	NodeType
+	Value bool
+}
+
+func (node *BoolNode) Copy() Node {
+	return newBoolNode(node.Value)
+}
+
+func (node *BoolNode) String() string {
+	return fmt.Sprint(node.Value)
+}
+
+type NilNode struct {
+	// Pos
+	NodeType
+}
+
+func (node *NilNode) Copy() Node {
+	return newNilNode()
+}
+
+func (node *NilNode) String() string {
+	return "nil"
+}
+
+type KeywordNode struct {
+	// Pos
+	NodeType
+	Name string
+}
+
+func (node *KeywordNode) Copy() Node {
+	return NewKeywordNode(node.Name)
+}
+
+func (node *KeywordNode) String() string {
+	return ":" + node.Name
+}
+
 type StringNode struct {
 	// Pos
 	NodeType
@@ -65,6 +153,23 @@ func (node *StringNode) String() string {
 	return node.Value
 }
 
+type CharNode struct {
+	// Pos
+	NodeType
+	Value rune
+}
+
+func (node *CharNode) Copy() Node {
+	return newCharNode(node.Value)
+}
+
+func (node *CharNode) String() string {
+	return fmt.Sprintf("%q", node.Value)
+}
+
+// NumberNode is either a NodeNumber, in which case NumberType is the
+// kind of Go literal it is, or a NodeBigInt/NodeRatio (with Value
+// being what math/big parses).
 type NumberNode struct {
 	// Pos
 	NodeType
@@ -98,6 +203,58 @@ func (node *VectorNode) String() string {
 	return fmt.Sprint(node.Nodes)
 }
 
+type MapNode struct {
+	// Pos
+	NodeType
+	Nodes []Node
+}
+
+func (node *MapNode) Copy() Node {
+	m := &MapNode{NodeType: node.Type(), Nodes: make([]Node, len(node.Nodes))}
+	for i, v := range node.Nodes {
+		m.Nodes[i] = v.Copy()
+	}
+	return m
+}
+
+func (node *MapNode) String() string {
+	nodes := fmt.Sprint(node.Nodes)
+	return "{" + nodes[1:len(nodes)-1] + "}"
+}
+
+type SetNode struct {
+	// Pos
+	NodeType
+	Nodes []Node
+}
+
+func (node *SetNode) Copy() Node {
+	set := &SetNode{NodeType: node.Type(), Nodes: make([]Node, len(node.Nodes))}
+	for i, v := range node.Nodes {
+		set.Nodes[i] = v.Copy()
+	}
+	return set
+}
+
+func (node *SetNode) String() string {
+	nodes := fmt.Sprint(node.Nodes)
+	return "#{" + nodes[1:len(nodes)-1] + "}"
+}
+
+type RegexNode struct {
+	// Pos
+	NodeType
+	Pattern string
+}
+
+func (node *RegexNode) Copy() Node {
+	return newRegexNode(node.Pattern)
+}
+
+func (node *RegexNode) String() string {
+	return "#\"" + node.Pattern + "\""
+}
+
 type CallNode struct {
 	// Pos
 	NodeType
@@ -118,7 +275,6 @@ func (node *CallNode) String() string {
 	return fmt.Sprintf("(%s %s)", node.Callee, args[1:len(args)-1])
 }
 
-var nilNode = NewIdentNode("nil")
 
 func ParseFromString(name, program string) []Node {
 	return Parse(lexer.Lex(name, program))
@@ -129,51 +285,143 @@ func Parse(l *lexer.Lexer) []Node {
 }
 
 func parser(l *lexer.Lexer, tree []Node, lookingFor rune) []Node {
+	// number of upcoming forms to drop, because of #_
+	discard := 0
+	// forms read after a ^, for the next form to be tagged with
+	pendingMeta, meta := 0, []Node{}
+
 	for item := l.NextItem(); item.Type != lexer.ItemEOF; {
+		parsed := len(tree)
+
 		switch t := item.Type; t {
 		case lexer.ItemIdent:
-			tree = append(tree, NewIdentNode(item.Value))
+			switch item.Value {
+			case "true", "false":
+				tree = append(tree, newBoolNode(item.Value == "true"))
+			case "nil":
+				tree = append(tree, newNilNode())
+			default:
+				tree = append(tree, NewIdentNode(item.Value))
+			}
+		case lexer.ItemKeyword:
+			tree = append(tree, NewKeywordNode(item.Value[1:]))
 		case lexer.ItemString:
 			tree = append(tree, newStringNode(item.Value))
+		case lexer.ItemChar:
+			r, _ := lexer.CharValue(item.Value[1:])
+			tree = append(tree, newCharNode(r))
+		case lexer.ItemTypeHint:
+			tree = append(tree, newTypeHintNode(item.Value[1:]))
+		case lexer.ItemRegex:
+			tree = append(tree, newRegexNode(item.Value[2:len(item.Value)-1]))
 		case lexer.ItemInt:
 			tree = append(tree, newIntNode(item.Value))
 		case lexer.ItemFloat:
 			tree = append(tree, newFloatNode(item.Value))
 		case lexer.ItemComplex:
 			tree = append(tree, newComplexNode(item.Value))
+		case lexer.ItemBigInt:
+			tree = append(tree, newBigIntNode(item.Value[:len(item.Value)-1]))
+		case lexer.ItemRatio:
+			tree = append(tree, newRatioNode(item.Value))
 		case lexer.ItemLeftParen:
 			tree = append(tree, newCallNode(parser(l, make([]Node, 0), ')')))
 		case lexer.ItemLeftVect:
 			tree = append(tree, newVectNode(parser(l, make([]Node, 0), ']')))
+		case lexer.ItemLeftMap:
+			tree = append(tree, newMapNode(parser(l, make([]Node, 0), '}')))
+		case lexer.ItemLeftSet:
+			tree = append(tree, newSetNode(parser(l, make([]Node, 0), '}')))
+		case lexer.ItemLeftFn:
+			tree = append(tree, newAnonFnNode(newCallNode(parser(l, make([]Node, 0), ')'))))
+		case lexer.ItemDiscard:
+			discard++
+		case lexer.ItemMeta:
+			pendingMeta++
 		case lexer.ItemRightParen:
+			checkNoMeta(pendingMeta, meta)
 			if lookingFor != ')' {
 				panic(fmt.Sprintf("unexpected \")\" [%d]", item.Pos))
 			}
 			return tree
 		case lexer.ItemRightVect:
+			checkNoMeta(pendingMeta, meta)
 			if lookingFor != ']' {
 				panic(fmt.Sprintf("unexpected \"]\" [%d]", item.Pos))
 			}
 			return tree
+		case lexer.ItemRightMap:
+			checkNoMeta(pendingMeta, meta)
+			if lookingFor != '}' {
+				panic(fmt.Sprintf("unexpected \"}\" [%d]", item.Pos))
+			}
+			return tree
 		case lexer.ItemError:
-			println(item.Value)
+			panic(item.Value)
 		default:
 			panic("Bad Item type")
 		}
+
+		if pendingMeta > 0 && len(tree) > parsed {
+			meta = append(meta, tree[parsed])
+			tree = tree[:parsed]
+			pendingMeta--
+		} else if len(meta) > 0 && len(tree) > parsed {
+			withMeta(tree[parsed], meta)
+			meta = []Node{}
+		}
+
+		if discard > 0 && len(tree) > parsed {
+			tree = tree[:parsed]
+			discard--
+		}
+
 		item = l.NextItem()
 	}
 
+	checkNoMeta(pendingMeta, meta)
 	return tree
 }
 
+func withMeta(node Node, meta []Node) {
+	ident, ok := node.(*IdentNode)
+	if !ok {
+		panic(fmt.Sprintf("metadata can only be attached to identifiers, not: %s", node))
+	}
+
+	ident.Meta = append(ident.Meta, meta...)
+}
+
+func checkNoMeta(pending int, meta []Node) {
+	if pending > 0 || len(meta) > 0 {
+		panic("metadata (^) needs to be followed by a form to attach it to")
+	}
+}
+
 func NewIdentNode(name string) *IdentNode {
 	return &IdentNode{NodeType: NodeIdent, Ident: name}
 }
 
+func NewKeywordNode(name string) *KeywordNode {
+	return &KeywordNode{NodeType: NodeKeyword, Name: name}
+}
+
 func newStringNode(val string) *StringNode {
 	return &StringNode{NodeType: NodeString, Value: val}
 }
 
+func newBoolNode(val bool) *BoolNode {
+	return &BoolNode{NodeType: NodeBool, Value: val}
+}
+
+func newNilNode() *NilNode {
+	return &NilNode{NodeType: NodeNil}
+}
+
+func newCharNode(val rune) *CharNode {
+	return &CharNode{NodeType: NodeChar, Value: val}
+}
+
 func newIntNode(val string) *NumberNode {
 	return &NumberNode{NodeType: NodeNumber, Value: val, NumberType: token.INT}
 }
@@ -186,15 +434,96 @@ func newComplexNode(val string) *NumberNode {
 	return &NumberNode{NodeType: NodeNumber, Value: val, NumberType: token.IMAG}
 }
 
+func newBigIntNode(val string) *NumberNode {
+	return &NumberNode{NodeType: NodeBigInt, Value: val, NumberType: token.INT}
+}
+
+func newRatioNode(val string) *NumberNode {
+	return &NumberNode{NodeType: NodeRatio, Value: val, NumberType: token.QUO}
+}
+
 // We return Node here, because it could be that it's nil
 func newCallNode(args []Node) Node {
 	if len(args) > 0 {
 		return &CallNode{NodeType: NodeCall, Callee: args[0], Args: args[1:]}
 	} else {
-		return nilNode
+		return newNilNode()
 	}
 }
 
 func newVectNode(content []Node) *VectorNode {
 	return &VectorNode{NodeType: NodeVector, Nodes: content}
 }
+
+func newMapNode(content []Node) *MapNode {
+	return &MapNode{NodeType: NodeMap, Nodes: content}
+}
+
+func newSetNode(content []Node) *SetNode {
+	return &SetNode{NodeType: NodeSet, Nodes: content}
+}
+
+func newTypeHintNode(typ string) *TypeHintNode {
+	return &TypeHintNode{NodeType: NodeTypeHint, Expr: typ}
+}
+
+func newRegexNode(pattern string) *RegexNode {
+	return &RegexNode{NodeType: NodeRegex, Pattern: pattern}
+}
+
+// newAnonFnNode turns #(+ % %2) into (fn [%1 %2] (+ %1 %2)),
+// %& being the rest arguments.
+func newAnonFnNode(body Node) *CallNode {
+	arity, rest := anonFnArgs(body)
+
+	params := make([]Node, 0, arity+2)
+	for i := 1; i <= arity; i++ {
+		params = append(params, NewIdentNode(fmt.Sprintf("%%%d", i)))
+	}
+
+	if rest {
+		params = append(params, NewIdentNode("&"), NewIdentNode("%&"))
+	}
+
+	return &CallNode{NodeType: NodeCall, Callee: NewIdentNode("fn"), Args: []Node{newVectNode(params), body}}
+}
+
+// anonFnArgs renames % to %1 and returns the highest %N used,
+// leaving the arguments of nested fns alone.
+func anonFnArgs(node Node) (int, bool) {
+	arity, rest := 0, false
+
+	var children []Node
+	switch n := node.(type) {
+	case *IdentNode:
+		switch {
+		case n.Ident == "%":
+			n.Ident = "%1"
+			return 1, false
+		case n.Ident == "%&":
+			return 0, true
+		case len(n.Ident) > 1 && n.Ident[0] == '%':
+			fmt.Sscanf(n.Ident[1:], "%d", &arity)
+			return arity, false
+		}
+	case *CallNode:
+		if ident, ok := n.Callee.(*IdentNode); ok && ident.Ident == "fn" {
+			return 0, false
+		}
+		children = append([]Node{n.Callee}, n.Args...)
+	case *VectorNode:
+		children = n.Nodes
+	case *MapNode:
+		children = n.Nodes
+	case *SetNode:
+		children = n.Nodes
+	}
+
+	for _, child := range children {
+		n, r := anonFnArgs(child)
+		arity = max(arity, n)
+		rest = rest || r
+	}
+
+	return arity, rest
+}
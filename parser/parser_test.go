@@ -0,0 +1,143 @@
+package parser
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func ident(name string) *IdentNode { return NewIdentNode(name) }
+
+func call(callee Node, args ...Node) *CallNode {
+	return &CallNode{NodeType: NodeCall, Callee: callee, Args: append([]Node{}, args...)}
+}
+
+var parseTests = []struct {
+	name  string
+	input string
+	tree  []Node
+}{
+	{"empty", "", []Node{}},
+	{"atoms", `a "b" 12 1.5`, []Node{
+		ident("a"),
+		newStringNode(`"b"`),
+		newIntNode("12"),
+		newFloatNode("1.5"),
+	}},
+	{"chars and raw strings", "\\a \\newline `b\\n`", []Node{
+		newCharNode('a'),
+		newCharNode('\n'),
+		newStringNode("`b\\n`"),
+	}},
+	{"bool and nil", "true false nil", []Node{
+		newBoolNode(true),
+		newBoolNode(false),
+		newNilNode(),
+	}},
+	{"big numbers", "123N -1/3 0x1F", []Node{
+		newBigIntNode("123"),
+		newRatioNode("-1/3"),
+		newIntNode("0x1F"),
+	}},
+	{"meta", "(def ^:export ^{:doc \"x\"} x 1)", []Node{
+		call(ident("def"), &IdentNode{NodeType: NodeIdent, Ident: "x", Meta: []Node{NewKeywordNode("export"), newMapNode([]Node{NewKeywordNode("doc"), newStringNode(`"x"`)})}}, newIntNode("1")),
+	}},
+	{"type hints", "(f ^[]int xs) ^map[string]T", []Node{
+		call(ident("f"), newTypeHintNode("[]int"), ident("xs")),
+		newTypeHintNode("map[string]T"),
+	}},
+	{"call", "(f x (g))", []Node{
+		call(ident("f"), ident("x"), call(ident("g"))),
+	}},
+	{"empty call is nil", "()", []Node{
+		newNilNode(),
+	}},
+	{"vector", "[x [y]]", []Node{
+		newVectNode([]Node{ident("x"), newVectNode([]Node{ident("y")})}),
+	}},
+	{"keyword", ":a-b", []Node{
+		NewKeywordNode("a-b"),
+	}},
+	{"map", "{:a [x y]}", []Node{
+		newMapNode([]Node{NewKeywordNode("a"), newVectNode([]Node{ident("x"), ident("y")})}),
+	}},
+	{"set", "#{12 :a}", []Node{
+		newSetNode([]Node{newIntNode("12"), NewKeywordNode("a")}),
+	}},
+	{"regex", `#"a\d+"`, []Node{
+		newRegexNode(`a\d+`),
+	}},
+	{"discard", "a #_ (b c) d #_ #_ e f g", []Node{
+		ident("a"), ident("d"), ident("g"),
+	}},
+	{"anonymous fn", "#(+ % %3)", []Node{
+		call(ident("fn"), newVectNode([]Node{ident("%1"), ident("%2"), ident("%3")}), call(ident("+"), ident("%1"), ident("%3"))),
+	}},
+	{"anonymous fn rest", "#(f %&)", []Node{
+		call(ident("fn"), newVectNode([]Node{ident("&"), ident("%&")}), call(ident("f"), ident("%&"))),
+	}},
+	{"anonymous fn nested fn", "#(f % (fn [x] %2))", []Node{
+		call(ident("fn"), newVectNode([]Node{ident("%1")}), call(ident("f"), ident("%1"), call(ident("fn"), newVectNode([]Node{ident("x")}), ident("%2")))),
+	}},
+}
+
+func TestParse(t *testing.T) {
+	for _, test := range parseTests {
+		if tree := ParseFromString("test", test.input); !reflect.DeepEqual(tree, test.tree) {
+			t.Errorf("%s: got %v, want %v", test.name, tree, test.tree)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, input := range []string{"(a]", "[a)", "{a)", "a)", "a}", `"\q"`, "\\nope", "^:a", "(f ^:a)", "^:a 1"} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("%q: parsed, want a panic", input)
+				}
+			}()
+			ParseFromString("test", input)
+		}()
+	}
+}
+
+func TestCopy(t *testing.T) {
+	tree := ParseFromString("test", `(f [x {:a "b"}] 1)`)
+	copied := tree[0].Copy()
+
+	if !reflect.DeepEqual(copied, tree[0]) {
+		t.Errorf("got %v, want %v", copied, tree[0])
+	}
+
+	copied.(*CallNode).Args[0].(*VectorNode).Nodes[0] = ident("y")
+	if fmt.Sprint(tree[0]) != `(f [x {:a "b"}] 1)` {
+		t.Errorf("changing the copy changed the original: %v", tree[0])
+	}
+}
+
+func TestHasMeta(t *testing.T) {
+	tests := []struct {
+		input string
+		key   string
+		has   bool
+	}{
+		{"^:export x", "export", true},
+		{"^:private ^:export x", "export", true},
+		{"^{:export true} x", "export", true},
+		{"^{:export false} x", "export", false},
+		{"^:private x", "export", false},
+		{"x", "export", false},
+	}
+
+	for _, test := range tests {
+		ident := ParseFromString("test", test.input)[0].(*IdentNode)
+		if has := ident.HasMeta(test.key); has != test.has {
+			t.Errorf("%q: HasMeta(%q) = %v, want %v", test.input, test.key, has, test.has)
+		}
+
+		if copied := ident.Copy().(*IdentNode); copied.HasMeta(test.key) != test.has {
+			t.Errorf("%q: the copy lost its metadata", test.input)
+		}
+	}
+}